@@ -0,0 +1,74 @@
+package clover
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	// BagCtxKey is the context.Context key to store the request Bag.
+	BagCtxKey = &contextKey{"Bag"}
+)
+
+// Bag is a concurrency-safe store of request scoped values, shared by the
+// middlewares and the handler of a request, ie. to accumulate warnings. It's
+// stored in the request context by middleware.WithBag, or WithBag.
+type Bag struct {
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+// NewBag returns an empty Bag.
+func NewBag() *Bag {
+	return &Bag{values: make(map[string]interface{})}
+}
+
+// WithBag returns a copy of ctx carrying a new Bag.
+func WithBag(ctx context.Context) context.Context {
+	return context.WithValue(ctx, BagCtxKey, NewBag())
+}
+
+// BagFrom returns the Bag of ctx, or nil if there is none.
+func BagFrom(ctx context.Context) *Bag {
+	b, _ := ctx.Value(BagCtxKey).(*Bag)
+	return b
+}
+
+// Set stores the value under key.
+func (b *Bag) Set(key string, value interface{}) {
+	b.mu.Lock()
+	b.values[key] = value
+	b.mu.Unlock()
+}
+
+// Get returns the value stored under key.
+func (b *Bag) Get(key string) (interface{}, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	v, ok := b.values[key]
+	return v, ok
+}
+
+// Append appends the values to the []interface{} stored under key.
+func (b *Bag) Append(key string, values ...interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	vs, _ := b.values[key].([]interface{})
+	b.values[key] = append(vs, values...)
+}
+
+// BagValue returns the value stored under key in the Bag of ctx as T, and
+// whether there is such a value of type T.
+func BagValue[T any](ctx context.Context, key string) (T, bool) {
+	var zero T
+	b := BagFrom(ctx)
+	if b == nil {
+		return zero, false
+	}
+	v, ok := b.Get(key)
+	if !ok {
+		return zero, false
+	}
+	t, ok := v.(T)
+	return t, ok
+}
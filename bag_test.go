@@ -0,0 +1,46 @@
+package clover
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestBag(t *testing.T) {
+	r := NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithBag(r.Context())))
+		})
+	})
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b := BagFrom(r.Context())
+			b.Set("user", "clover")
+			b.Append("warnings", "deprecated endpoint")
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.MethodFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+		BagFrom(r.Context()).Append("warnings", "slow query")
+
+		user, _ := BagValue[string](r.Context(), "user")
+		warnings, _ := BagValue[[]interface{}](r.Context(), "warnings")
+		if _, ok := BagValue[int](r.Context(), "user"); ok {
+			t.Fatal("expected a type mismatch")
+		}
+		w.Write([]byte(fmt.Sprintln(user, warnings)))
+	})
+
+	if _, body := testHandler(t, r, "GET", "/", nil); body != "clover [deprecated endpoint slow query]\n" {
+		t.Fatalf(body)
+	}
+
+	if BagFrom(context.Background()) != nil {
+		t.Fatal("expected no bag")
+	}
+	if _, ok := BagValue[string](context.Background(), "user"); ok {
+		t.Fatal("expected no value without a bag")
+	}
+}
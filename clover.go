@@ -1,6 +1,6 @@
 // Package clover is a small, idiomatic and composable router for building HTTP services.
 //
-// clover requires Go 1.14 or newer.
+// clover requires Go 1.19 or newer.
 //
 // Example:
 //
@@ -40,24 +40,32 @@
 // syntax is Go's normal regexp RE2 syntax, except that regular expressions
 // including { or } are not supported, and / will never be
 // matched. An anonymous regexp pattern is allowed, using an empty string
-// before the colon in the placeholder, such as {:\\d+}
+// before the colon in the placeholder, such as {:\\d+}. The values of
+// anonymous patterns are keyed by their position among the anonymous
+// patterns of the route, ie. clover.URLParam(r, "0").
 //
 // The special placeholder of asterisk matches the rest of the requested
 // URL. Any trailing characters in the pattern are ignored. This is the only
-// placeholder which will match / characters.
+// placeholder which will match / characters. The matched remainder of the
+// URL is available under the "*" key, ie. clover.URLParam(r, "*").
 //
 // Examples:
 //
 //	"/user/{name}" matches "/user/jsmith" but not "/user/jsmith/info" or "/user/jsmith/"
 //	"/user/{name}/info" matches "/user/jsmith/info"
-//	"/page/*" matches "/page/intro/latest"
+//	"/page/*" matches "/page/intro/latest", with "*" set to "intro/latest"
 //	"/page/{other}/index" also matches "/page/intro/latest"
 //	"/date/{yyyy:\\d\\d\\d\\d}/{mm:\\d\\d}/{dd:\\d\\d}" matches "/date/2017/04/01"
+//	"/date/{:\\d\\d\\d\\d}/{:\\d\\d}" matches "/date/2017/04", with "0" set to "2017" and "1" to "04"
 package clover
 
 import (
 	"context"
+	"io"
+	"log"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/goclover/clover/render"
 )
@@ -67,6 +75,7 @@ func New() *Clover {
 	c := &Clover{
 		Mux: newMux(),
 	}
+	c.ready.Store(true)
 	return c
 }
 
@@ -83,9 +92,19 @@ type Router interface {
 	// Use appends one or more middlewares onto the Router stack.
 	Use(middlewares ...func(http.Handler) http.Handler)
 
+	// UseIf appends the middlewares onto the Router stack when cond is true.
+	UseIf(cond bool, middlewares ...func(http.Handler) http.Handler)
+
 	// With adds inline middlewares for an endpoint handler.
 	With(middlewares ...func(http.Handler) http.Handler) Router
 
+	// UseStack defines a named middleware stack, applied to routes with
+	// Stack.
+	UseStack(name string, middlewares ...func(http.Handler) http.Handler)
+
+	// Stack adds the middlewares of the named stacks inline, like With.
+	Stack(names ...string) Router
+
 	// Group adds a new inline-Router along the current routing
 	// path, with a fresh middleware stack for the inline-Router.
 	Group(fn func(r Router)) Router
@@ -93,9 +112,20 @@ type Router interface {
 	// Route mounts a sub-Router along a `pattern`` string.
 	Route(pattern string, fn func(r Router)) Router
 
+	// RouteIf mounts a sub-Router along a `pattern`` string when cond is true.
+	RouteIf(cond bool, pattern string, fn func(r Router)) Router
+
+	// PathPrefix mounts a sub-Router along a `pattern`` string and returns
+	// it, without a callback.
+	PathPrefix(pattern string) Router
+
 	// Mount attaches another http.Handler along ./pattern/*
 	Mount(pattern string, h http.Handler)
 
+	// AsMux returns the *Mux backing the Router, ie. to inspect the routes
+	// of a sub-Router in isolation.
+	AsMux() *Mux
+
 	// Handle and HandleStd and HandleFunc adds routes for `pattern` that matches
 	// all HTTP methods.
 	Handle(pattern string, h HandlerFunc)
@@ -103,18 +133,52 @@ type Router interface {
 	HandleFunc(pattern string, h http.HandlerFunc)
 
 	// Method and MethodFunc adds routes for `pattern` that matches
-	// the `method` HTTP method.
+	// the `method` HTTP method. Registering a method and pattern twice, or a
+	// pattern naming its params differently than an existing route of the
+	// same method, panics.
 	Method(method, pattern string, h HandlerFunc)
 	MethodStd(method, pattern string, h http.Handler)
 	MethodFunc(method, pattern string, h http.HandlerFunc)
 
+	// RegisterRoutes adds the routes of a routing table.
+	RegisterRoutes(routes []RouteDef)
+
+	// Register adds a route for `pattern` that matches the `method` HTTP
+	// method, or all of them for "*", and returns a handle to configure it.
+	Register(method, pattern string, h http.Handler) *RouteHandle
+
+	// MatchFunc adds a route for `pattern` that matches all HTTP methods,
+	// guarded by the `match` predicate on the request.
+	MatchFunc(match func(r *http.Request) bool, pattern string, h http.HandlerFunc)
+
+	// Doc attaches documentation metadata to the route for `method` and
+	// `pattern`, exposed through Routes().
+	Doc(method, pattern string, doc RouteDoc)
+
+	// Accepts restricts the request Content-Types of the route for `method`
+	// and `pattern`, responding 415 Unsupported Media Type otherwise.
+	Accepts(method, pattern string, contentTypes ...string)
+
+	// Alias adds routes for the `aliases` patterns sharing the handlers
+	// of the `canonical` pattern.
+	Alias(canonical string, aliases ...string)
+
+	// Fallback defines a handler invoked whenever a route could not be
+	// found, before and instead of the NotFound handler.
+	Fallback(h HandlerFunc)
+
 	// NotFound defines a handler to respond whenever a route could
 	// not be found.
 	NotFound(h http.HandlerFunc)
+	NotFoundFunc(h HandlerFunc)
 
 	// MethodNotAllowed defines a handler to respond whenever a method is
 	// not allowed.
 	MethodNotAllowed(h http.HandlerFunc)
+	MethodNotAllowedFunc(h HandlerFunc)
+
+	// OnRoute registers a callback invoked whenever a route is registered.
+	OnRoute(fn func(method, pattern string, h http.Handler))
 }
 
 // Routes interface adds two methods for router traversal, which is also
@@ -139,34 +203,151 @@ type Middlewares []func(http.Handler) http.Handler
 // HandlerFunc type is a func implement of http.Handler
 type HandlerFunc func(c context.Context, r *http.Request) render.Render
 
-// ServeHTTP is the single method of the http.Handler interface that makes it work
+// ErrorLog specifies an optional logger for errors returned while writing a
+// render.Render to the client. If nil, logging is done via the log package's
+// standard logger.
+var ErrorLog *log.Logger
+
+// Debug controls whether the error returned while writing a render.Render is
+// included in the 500 response body. It's off by default, as error messages
+// may leak internal details to clients, and should only be enabled during
+// development.
+var Debug bool
+
+// ServeHTTP is the single method of the http.Handler interface that makes it work.
+// The body of the render is discarded for HEAD requests, while its headers,
+// Content-Length included, are kept.
 func (h HandlerFunc) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodHead {
+		w = headResponseWriter{w}
+	}
 	r := h(req.Context(), req)
 	if err := r.WriteTo(w); err != nil {
+		logf("clover: error writing response for %s %s: %v", req.Method, req.URL.Path, err)
+
+		body := http.StatusText(http.StatusInternalServerError)
+		if Debug {
+			body += ": " + err.Error()
+		}
+		// The render may have set headers for the body it failed to write,
+		// which would leave clients waiting for bytes that never come. This
+		// is a no-op when the headers have already been written.
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusInternalServerError)
-		_, _ = w.Write([]byte(err.Error()))
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+// headResponseWriter discards the body written in response to a HEAD request.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// ReadFrom keeps io.Copy from reading the body at all.
+func (w headResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	return 0, nil
+}
+
+func (w headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func logf(format string, args ...interface{}) {
+	if ErrorLog != nil {
+		ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
 	}
 }
 
 type Clover struct {
 	*Mux
 	Ser *http.Server
+
+	ready      atomic.Bool
+	drainDelay time.Duration
 }
 
-func (c *Clover) Run(addr string) error {
-	if c.Ser == nil {
-		c.Ser = &http.Server{}
+// Ready returns the readiness flag of the Clover, set until Shutdown is
+// called, for a readiness probe to report whether the server takes new
+// traffic, ie. with middleware.Readiness. It may also be cleared until the
+// service is warmed up.
+func (c *Clover) Ready() *atomic.Bool {
+	return &c.ready
+}
+
+// WithDrainDelay sets how long Shutdown waits between clearing the Ready
+// flag and closing the listeners, leaving time for load balancers polling the
+// readiness probe to stop sending new requests, ie. a few probe periods.
+func (c *Clover) WithDrainDelay(d time.Duration) *Clover {
+	c.drainDelay = d
+	return c
+}
+
+// Shutdown gracefully shuts down the server: the Ready flag is cleared
+// first, and once the drain delay has passed the server stops accepting new
+// connections and waits for the in-flight requests to finish, see
+// http.Server.Shutdown. It returns the context error if ctx is done before.
+func (c *Clover) Shutdown(ctx context.Context) error {
+	c.ready.Store(false)
+	if c.drainDelay > 0 {
+		t := time.NewTimer(c.drainDelay)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
+	return c.server().Shutdown(ctx)
+}
+
+// MaxHeaderBytes sets the maximum size of the request headers read by the
+// server, see http.Server.MaxHeaderBytes. Requests with larger headers are
+// answered with a 431 Request Header Fields Too Large before reaching the
+// router. It must be called before Run.
+func (c *Clover) MaxHeaderBytes(n int) *Clover {
+	c.server().MaxHeaderBytes = n
+	return c
+}
+
+// WithTimeouts sets the read, write and idle timeouts of the server, see
+// http.Server. The zero values of a bare http.Server leave connections open
+// indefinitely, which exposes it to slowloris attacks, so always set them
+// for public services. Sensible defaults for an API are a 5s read, 10s write
+// and 120s idle timeout, the write timeout being raised for slow handlers.
+// It must be called before Run.
+func (c *Clover) WithTimeouts(read, write, idle time.Duration) *Clover {
+	srv := c.server()
+	srv.ReadTimeout = read
+	srv.WriteTimeout = write
+	srv.IdleTimeout = idle
+	return c
+}
+
+func (c *Clover) Run(addr string) error {
+	c.server()
 	c.Ser.Addr = addr
 	c.Ser.Handler = c
 	return c.Ser.ListenAndServe()
 }
 
 func (c *Clover) RunTLS(addr string, certFile, keyFile string) error {
-	if c.Ser == nil {
-		c.Ser = &http.Server{}
-	}
+	c.server()
 	c.Ser.Addr = addr
 	c.Ser.Handler = c
 	return c.Ser.ListenAndServeTLS(certFile, keyFile)
 }
+
+// server returns the http.Server used to run the Clover, creating it if needed.
+func (c *Clover) server() *http.Server {
+	if c.Ser == nil {
+		c.Ser = &http.Server{}
+	}
+	return c.Ser
+}
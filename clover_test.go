@@ -0,0 +1,148 @@
+package clover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goclover/clover/render"
+)
+
+type errRender struct {
+	err error
+}
+
+func (e errRender) WriteTo(w http.ResponseWriter) error {
+	return e.err
+}
+
+func TestHandlerFuncErrorBody(t *testing.T) {
+	logbuf := &bytes.Buffer{}
+	defer func(l *log.Logger, d bool) { ErrorLog, Debug = l, d }(ErrorLog, Debug)
+	ErrorLog = log.New(logbuf, "", 0)
+
+	h := HandlerFunc(func(ctx context.Context, r *http.Request) render.Render {
+		return errRender{errors.New("db password is hunter2")}
+	})
+
+	Debug = false
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	if body := w.Body.String(); body != "Internal Server Error" {
+		t.Fatalf("expected generic body, got %q", body)
+	}
+	if !strings.Contains(logbuf.String(), "hunter2") {
+		t.Fatalf("expected error to be logged, got %q", logbuf.String())
+	}
+
+	Debug = true
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if body := w.Body.String(); body != "Internal Server Error: db password is hunter2" {
+		t.Fatalf("expected detailed body in debug mode, got %q", body)
+	}
+}
+
+func TestCloverMaxHeaderBytes(t *testing.T) {
+	c := New().MaxHeaderBytes(4 << 10)
+	if c.Ser == nil || c.Ser.MaxHeaderBytes != 4<<10 {
+		t.Fatalf("expected MaxHeaderBytes to be applied to the server")
+	}
+
+	c = New()
+	c.Ser = &http.Server{ReadTimeout: time.Second}
+	c.MaxHeaderBytes(1 << 10)
+	if c.Ser.MaxHeaderBytes != 1<<10 || c.Ser.ReadTimeout != time.Second {
+		t.Fatalf("expected MaxHeaderBytes to be applied to the existing server")
+	}
+}
+
+func TestCloverWithTimeouts(t *testing.T) {
+	c := New().WithTimeouts(5*time.Second, 10*time.Second, 2*time.Minute)
+	if c.Ser.ReadTimeout != 5*time.Second || c.Ser.WriteTimeout != 10*time.Second || c.Ser.IdleTimeout != 2*time.Minute {
+		t.Fatalf("unexpected server timeouts: %v %v %v", c.Ser.ReadTimeout, c.Ser.WriteTimeout, c.Ser.IdleTimeout)
+	}
+}
+
+func TestHandlerFuncHead(t *testing.T) {
+	r := NewRouter()
+	r.Method("GET", "/user", func(ctx context.Context, r *http.Request) render.Render {
+		return render.JSON(map[string]string{"name": "clover"})
+	})
+	r.Method("HEAD", "/user", func(ctx context.Context, r *http.Request) render.Render {
+		return render.JSON(map[string]string{"name": "clover"})
+	})
+	r.Method("HEAD", "/file", func(ctx context.Context, r *http.Request) render.Render {
+		return render.Reader(http.StatusOK, "text/plain", 8, strings.NewReader("contents"))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/user", nil))
+	getLength := w.Header().Get("Content-Length")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/user", nil))
+	if w.Code != 200 || w.Body.Len() != 0 {
+		t.Fatalf("expected an empty 200 response, got %d %q", w.Code, w.Body.String())
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "17" || cl != getLength {
+		t.Fatalf("unexpected Content-Length %q, GET has %q", cl, getLength)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Fatalf("unexpected Content-Type %q", ct)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/file", nil))
+	if w.Body.Len() != 0 || w.Header().Get("Content-Length") != "8" {
+		t.Fatalf("unexpected response: %v %q", w.Header(), w.Body.String())
+	}
+}
+
+type failingLengthRender struct{}
+
+func (failingLengthRender) WriteTo(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", "1000")
+	return errors.New("encoding failed")
+}
+
+func TestHandlerFuncErrorContentLength(t *testing.T) {
+	defer func(l *log.Logger) { ErrorLog = l }(ErrorLog)
+	ErrorLog = log.New(io.Discard, "", 0)
+
+	ts := httptest.NewServer(HandlerFunc(func(ctx context.Context, r *http.Request) render.Render {
+		return failingLengthRender{}
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error reading the body: %v", err)
+	}
+	if resp.StatusCode != 500 || string(body) != "Internal Server Error" {
+		t.Fatalf("unexpected response: %d %q", resp.StatusCode, body)
+	}
+	if resp.ContentLength != int64(len(body)) {
+		t.Fatalf("unexpected Content-Length %d", resp.ContentLength)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Fatalf("unexpected Content-Type %q", ct)
+	}
+}
@@ -0,0 +1,29 @@
+// Package clovertest provides utilities to unit test clover handlers without
+// serving HTTP requests.
+package clovertest
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/goclover/clover"
+	"github.com/goclover/clover/render"
+)
+
+// Invoke calls the handler with the request and returns the render.Render it
+// produced, so tests may assert on its type and fields directly. URL params
+// can be set on the request beforehand through a clover.Context stored under
+// clover.RouteCtxKey.
+func Invoke(h clover.HandlerFunc, req *http.Request) render.Render {
+	return h(req.Context(), req)
+}
+
+// RenderToRecorder writes the render to a new httptest.ResponseRecorder and
+// returns it, to assert on the response a render produces.
+func RenderToRecorder(r render.Render) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	if err := r.WriteTo(w); err != nil {
+		w.Code = http.StatusInternalServerError
+	}
+	return w
+}
@@ -0,0 +1,52 @@
+package clovertest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goclover/clover"
+	"github.com/goclover/clover/render"
+)
+
+func getUser(ctx context.Context, r *http.Request) render.Render {
+	id := clover.URLParam(r, "id")
+	if id == "" {
+		return render.Redirect(http.StatusFound, "/users")
+	}
+	return render.JSON(map[string]string{"id": id})
+}
+
+func TestInvoke(t *testing.T) {
+	rctx := clover.NewRouteContext()
+	rctx.URLParams.Add("id", "42")
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	req = req.WithContext(context.WithValue(req.Context(), clover.RouteCtxKey, rctx))
+
+	res, ok := Invoke(getUser, req).(*render.JSONRender)
+	if !ok {
+		t.Fatalf("expected a JSON render")
+	}
+	if res.Status != http.StatusOK || string(res.Data) != `{"id":"42"}` {
+		t.Fatalf("unexpected render: %d %s", res.Status, res.Data)
+	}
+
+	if _, ok := Invoke(getUser, httptest.NewRequest("GET", "/users/", nil)).(*render.RedirectRender); !ok {
+		t.Fatal("expected a redirect render")
+	}
+}
+
+func ExampleInvoke() {
+	req := httptest.NewRequest("GET", "/users/", nil)
+	res := Invoke(getUser, req)
+	fmt.Printf("%T\n", res)
+	// Output: *render.RedirectRender
+}
+
+func ExampleRenderToRecorder() {
+	w := RenderToRecorder(render.JSON(map[string]string{"id": "42"}))
+	fmt.Println(w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	// Output: 200 application/json; charset=utf-8 {"id":"42"}
+}
@@ -2,7 +2,9 @@ package clover
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -22,6 +24,97 @@ func URLParamFromCtx(ctx context.Context, key string) string {
 	return ""
 }
 
+// Param returns the url parameter from a http.Request object parsed as T,
+// which may be a string, a bool, a float or a signed or unsigned integer
+// type, ie. `id, err := clover.Param[int64](r, "id")`. It returns a
+// descriptive error if the parameter is missing or malformed.
+func Param[T any](r *http.Request, key string) (T, error) {
+	var v T
+	s := URLParam(r, key)
+	if s == "" {
+		return v, fmt.Errorf("clover: missing url param %q", key)
+	}
+
+	var err error
+	switch p := any(&v).(type) {
+	case *string:
+		*p = s
+	case *bool:
+		*p, err = strconv.ParseBool(s)
+	case *int:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, strconv.IntSize)
+		*p = int(n)
+	case *int8:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 8)
+		*p = int8(n)
+	case *int16:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 16)
+		*p = int16(n)
+	case *int32:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 32)
+		*p = int32(n)
+	case *int64:
+		*p, err = strconv.ParseInt(s, 10, 64)
+	case *uint:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, strconv.IntSize)
+		*p = uint(n)
+	case *uint8:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, 8)
+		*p = uint8(n)
+	case *uint16:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, 16)
+		*p = uint16(n)
+	case *uint32:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, 32)
+		*p = uint32(n)
+	case *uint64:
+		*p, err = strconv.ParseUint(s, 10, 64)
+	case *float32:
+		var f float64
+		f, err = strconv.ParseFloat(s, 32)
+		*p = float32(f)
+	case *float64:
+		*p, err = strconv.ParseFloat(s, 64)
+	default:
+		return v, fmt.Errorf("clover: unsupported type %T for url param %q", v, key)
+	}
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("clover: invalid url param %q=%q: %w", key, s, err)
+	}
+	return v, nil
+}
+
+// RoutePath returns the path the router attempted to match for a http.Request,
+// which for subrouters is the remainder of the path past the mount pattern.
+// It's handy to build informative NotFound and MethodNotAllowed responses.
+func RoutePath(r *http.Request) string {
+	if rctx := RouteContext(r.Context()); rctx != nil {
+		return rctx.attemptedPath
+	}
+	return ""
+}
+
+// RouteMiddlewares returns the middlewares applied to the route matched for
+// a http.Request, in execution order: the ones of each router it went
+// through set with Use, followed by the inline ones set with With or Group.
+// Like RoutePattern, it's complete once the request has been routed, ie.
+// in the handler or after calling the next handler in a middleware.
+func RouteMiddlewares(r *http.Request) Middlewares {
+	if rctx := RouteContext(r.Context()); rctx != nil {
+		return rctx.routeMiddlewares
+	}
+	return nil
+}
+
 // RouteContext returns clover's routing Context object from a
 // http.Request Context.
 func RouteContext(ctx context.Context) *Context {
@@ -76,6 +169,18 @@ type Context struct {
 
 	// methodNotAllowed hint
 	methodNotAllowed bool
+
+	// methods of the matched route, reported by the Allow header on 405
+	methodsAllowed []methodTyp
+
+	// middlewares of the routers and of the route matched, see
+	// RouteMiddlewares
+	routeMiddlewares Middlewares
+
+	// path the current router attempted to match, see RoutePath. It's kept
+	// apart from RoutePath, which overrides the path routed by any nested
+	// router, even when it isn't mounted.
+	attemptedPath string
 }
 
 // Reset a routing context to its initial state.
@@ -91,6 +196,9 @@ func (x *Context) Reset() {
 	x.routeParams.Keys = x.routeParams.Keys[:0]
 	x.routeParams.Values = x.routeParams.Values[:0]
 	x.methodNotAllowed = false
+	x.methodsAllowed = x.methodsAllowed[:0]
+	x.routeMiddlewares = x.routeMiddlewares[:0]
+	x.attemptedPath = ""
 	x.parentCtx = nil
 }
 
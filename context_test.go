@@ -1,6 +1,12 @@
 package clover
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
 
 // TestRoutePattern tests correct in-the-middle wildcard removals.
 // If user organizes a router like this:
@@ -76,3 +82,64 @@ func TestRoutePattern(t *testing.T) {
 		t.Fatal("unexpected route pattern: " + p)
 	}
 }
+
+// TestContextKeyCollision ensures values stored by users under string keys
+// named like clover's own keys don't clobber the routing context.
+func TestContextKeyCollision(t *testing.T) {
+	r := NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), "RouteContext", "user value")
+			ctx = context.WithValue(ctx, RouteCtxKey.String(), "another user value")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	r.MethodFunc("GET", "/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Context().Value("RouteContext"); v != "user value" {
+			t.Fatalf("unexpected user value: %v", v)
+		}
+		w.Write([]byte(URLParam(r, "id")))
+	})
+
+	if _, body := testHandler(t, r, "GET", "/users/42", nil); body != "42" {
+		t.Fatalf("unexpected url param: %q", body)
+	}
+}
+
+func TestParam(t *testing.T) {
+	r := NewRouter()
+	r.MethodFunc("GET", "/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := Param[int](r, "id")
+		if err != nil {
+			w.WriteHeader(400)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		id64, _ := Param[int64](r, "id")
+		w.Write([]byte(fmt.Sprintf("%d %d", id, id64)))
+	})
+	r.MethodFunc("GET", "/big/{n}", func(w http.ResponseWriter, r *http.Request) {
+		n, err := Param[int64](r, "n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Param[int32](r, "n"); err == nil {
+			t.Fatal("expected an out of range error for int32")
+		}
+		if _, err := Param[int](r, "missing"); err == nil || err.Error() != `clover: missing url param "missing"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write([]byte(fmt.Sprint(n)))
+	})
+
+	if _, body := testHandler(t, r, "GET", "/users/42", nil); body != "42 42" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "GET", "/big/8589934592", nil); body != "8589934592" {
+		t.Fatalf(body)
+	}
+	resp, body := testHandler(t, r, "GET", "/users/abc", nil)
+	if resp.StatusCode != 400 || !strings.HasPrefix(body, `clover: invalid url param "id"="abc": strconv.ParseInt`) {
+		t.Fatalf("unexpected response: %d %q", resp.StatusCode, body)
+	}
+}
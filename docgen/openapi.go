@@ -0,0 +1,138 @@
+// Package docgen generates API specifications from the routes of a clover
+// router and their documentation metadata, see clover.Mux.Doc.
+package docgen
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/goclover/clover"
+)
+
+// Spec is a minimal OpenAPI 3 document, which marshals to JSON.
+type Spec struct {
+	OpenAPI string                           `json:"openapi"`
+	Info    Info                             `json:"info"`
+	Paths   map[string]map[string]*Operation `json:"paths"`
+}
+
+// Info is the metadata of the API.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Operation describes a single method of a path.
+type Operation struct {
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a path parameter.
+type Parameter struct {
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required"`
+	Schema   interface{} `json:"schema"`
+}
+
+// RequestBody describes the JSON body of a request.
+type RequestBody struct {
+	Content map[string]*MediaType `json:"content"`
+}
+
+// Response describes a response.
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body.
+type MediaType struct {
+	Schema interface{} `json:"schema"`
+}
+
+var paramRe = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// OpenAPI returns an OpenAPI 3 skeleton of the paths and methods of the
+// routes, mounted subrouters included, filled with their documentation
+// metadata. The Info of the returned Spec is meant to be set by the caller.
+//
+// Routes registered for all methods, ie. with Handle, only list the methods
+// documented with Doc.
+func OpenAPI(r clover.Routes) *Spec {
+	spec := &Spec{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: "API", Version: "0.0.0"},
+		Paths:   map[string]map[string]*Operation{},
+	}
+	addRoutes(spec, r, "")
+	return spec
+}
+
+func addRoutes(spec *Spec, r clover.Routes, prefix string) {
+	for _, route := range r.Routes() {
+		pattern := strings.Replace(prefix+route.Pattern, "/*/", "/", -1)
+		if route.SubRoutes != nil {
+			addRoutes(spec, route.SubRoutes, strings.TrimSuffix(pattern, "/*"))
+			continue
+		}
+
+		_, all := route.Handlers["*"]
+		for method := range route.Handlers {
+			doc := route.Docs[method]
+			if method == "*" || (all && doc == nil) {
+				continue
+			}
+
+			path, params := openAPIPath(pattern)
+			if spec.Paths[path] == nil {
+				spec.Paths[path] = map[string]*Operation{}
+			}
+			spec.Paths[path][strings.ToLower(method)] = operation(doc, params)
+		}
+	}
+}
+
+// openAPIPath converts a routing pattern to an OpenAPI path, stripping the
+// regexps of its params, and returns the names of the params.
+func openAPIPath(pattern string) (string, []string) {
+	var params []string
+	for _, m := range paramRe.FindAllStringSubmatch(pattern, -1) {
+		params = append(params, m[1])
+	}
+	path := paramRe.ReplaceAllString(pattern, "{$1}")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path, params
+}
+
+func operation(doc *clover.RouteDoc, params []string) *Operation {
+	op := &Operation{Responses: map[string]*Response{"200": {Description: "OK"}}}
+	for _, name := range params {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name: name, In: "path", Required: true, Schema: map[string]string{"type": "string"},
+		})
+	}
+	if doc == nil {
+		return op
+	}
+
+	op.Summary = doc.Summary
+	op.Description = doc.Description
+	op.Tags = doc.Tags
+	op.Deprecated = doc.Deprecated
+	if doc.Request != nil {
+		op.RequestBody = &RequestBody{Content: map[string]*MediaType{"application/json": {Schema: doc.Request}}}
+	}
+	if doc.Response != nil {
+		op.Responses["200"].Content = map[string]*MediaType{"application/json": {Schema: doc.Response}}
+	}
+	return op
+}
@@ -0,0 +1,38 @@
+package docgen
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/goclover/clover"
+)
+
+func TestOpenAPI(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := clover.NewRouter()
+	r.MethodFunc("GET", "/", h)
+	r.HandleFunc("/ping", h)
+	r.Route("/users", func(r clover.Router) {
+		r.MethodFunc("GET", "/{id:[0-9]+}", h)
+		r.Doc("GET", "/{id:[0-9]+}", clover.RouteDoc{
+			Summary:  "Get a user",
+			Tags:     []string{"users"},
+			Response: map[string]string{"type": "object"},
+		})
+	})
+
+	b, err := json.Marshal(OpenAPI(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"openapi":"3.0.3","info":{"title":"API","version":"0.0.0"},"paths":{` +
+		`"/":{"get":{"responses":{"200":{"description":"OK"}}}},` +
+		`"/users/{id}":{"get":{"summary":"Get a user","tags":["users"],` +
+		`"parameters":[{"name":"id","in":"path","required":true,"schema":{"type":"string"}}],` +
+		`"responses":{"200":{"description":"OK","content":{"application/json":{"schema":{"type":"object"}}}}}}}}}`
+	if string(b) != expected {
+		t.Fatalf("unexpected spec:\n%s\nexpected:\n%s", b, expected)
+	}
+}
@@ -0,0 +1,73 @@
+package clover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/goclover/clover/render"
+)
+
+// StatusError is an error carrying the HTTP status of the response, which
+// JSONHandler uses to answer a failed call, ie.
+// `&clover.StatusError{Status: http.StatusNotFound, Err: err}`.
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (e *StatusError) Error() string {
+	if e.Err == nil {
+		return http.StatusText(e.Status)
+	}
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// JSONHandler returns a HandlerFunc decoding the JSON request body into In,
+// calling fn with it and rendering its Out result as JSON, which removes the
+// decode and render boilerplate of typical JSON APIs.
+//
+// A malformed body is answered with a 400 Bad Request, and an error returned
+// by fn with the status of a wrapped *StatusError, or 500 Internal Server
+// Error otherwise. Error responses have a {"error": "..."} body, where the
+// message of 5xx errors is only included when Debug is set.
+//
+//	r.Method("POST", "/users", clover.JSONHandler(createUser))
+func JSONHandler[In, Out any](fn func(ctx context.Context, in In) (Out, error)) HandlerFunc {
+	return func(ctx context.Context, r *http.Request) render.Render {
+		var in In
+		if r.Body != nil {
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil && err != io.EOF {
+				return jsonError(http.StatusBadRequest, err)
+			}
+		}
+
+		out, err := fn(ctx, in)
+		if err != nil {
+			status := http.StatusInternalServerError
+			var se *StatusError
+			if errors.As(err, &se) && se.Status > 0 {
+				status = se.Status
+			}
+			return jsonError(status, err)
+		}
+		return render.JSON(out)
+	}
+}
+
+// jsonError renders err as a JSON error response with the given status.
+func jsonError(status int, err error) render.Render {
+	msg := err.Error()
+	if status >= 500 && !Debug {
+		msg = http.StatusText(status)
+	}
+	res := render.JSON(map[string]string{"error": msg})
+	res.Status = status
+	return res
+}
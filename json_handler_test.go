@@ -0,0 +1,52 @@
+package clover
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONHandler(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+	type greeting struct {
+		Message string `json:"message"`
+	}
+
+	h := JSONHandler(func(ctx context.Context, in user) (greeting, error) {
+		switch in.Name {
+		case "":
+			return greeting{}, &StatusError{Status: http.StatusUnprocessableEntity, Err: errors.New("name is required")}
+		case "root":
+			return greeting{}, errors.New("db password is hunter2")
+		}
+		return greeting{Message: "hello " + in.Name}, nil
+	})
+
+	tests := []struct {
+		body   string
+		status int
+		resp   string
+	}{
+		{`{"name":"clover"}`, 200, `{"message":"hello clover"}`},
+		{`{"name":`, 400, `{"error":"unexpected EOF"}`},
+		{`{}`, 422, `{"error":"name is required"}`},
+		{``, 422, `{"error":"name is required"}`},
+		{`{"name":"root"}`, 500, `{"error":"Internal Server Error"}`},
+	}
+
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(tc.body)))
+		if w.Code != tc.status || w.Body.String() != tc.resp {
+			t.Fatalf("%q: expected %d %s, got %d %s", tc.body, tc.status, tc.resp, w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+			t.Fatalf("%q: unexpected content type %q", tc.body, ct)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// AllowedHosts is a middleware that rejects requests whose Host header isn't
+// one of the allowed hosts with a 421 Misdirected Request, mitigating host
+// header injection and cache poisoning. Hosts are matched case-insensitively
+// and regardless of the port, and a "*." prefix allows any subdomain, ie.
+// "*.example.com" allows "api.example.com" but not "example.com" itself.
+func AllowedHosts(hosts ...string) func(next http.Handler) http.Handler {
+	exact := make(map[string]struct{}, len(hosts))
+	var suffixes []string
+	for _, host := range hosts {
+		host = normalizeHost(strings.TrimSpace(host))
+		if strings.HasPrefix(host, "*.") {
+			suffixes = append(suffixes, host[1:])
+		} else {
+			exact[host] = struct{}{}
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			host := normalizeHost(r.Host)
+
+			if _, ok := exact[host]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, suffix := range suffixes {
+				if len(host) > len(suffix) && strings.HasSuffix(host, suffix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			http.Error(w, http.StatusText(http.StatusMisdirectedRequest), http.StatusMisdirectedRequest)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// normalizeHost returns the host of a Host header the way it's matched by DNS
+// rules, lowercased and without its port and trailing dot, ie. "example.com"
+// for "EXAMPLE.com.:443".
+func normalizeHost(host string) string {
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
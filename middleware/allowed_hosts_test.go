@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowedHosts(t *testing.T) {
+	h := AllowedHosts("example.com", "*.example.org")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	tests := []struct {
+		host   string
+		status int
+	}{
+		{"example.com", 200},
+		{"EXAMPLE.com:8080", 200},
+		{"EXAMPLE.com.:443", 200},
+		{"[::1]:8080", 421},
+		{"api.example.org", 200},
+		{"a.b.example.org:443", 200},
+		{"example.org", 421},
+		{"evil.com", 421},
+		{"example.com.evil.com", 421},
+		{"evilexample.org", 421},
+		{"", 421},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Host = tc.host
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Fatalf("%q: expected %d, got %d", tc.host, tc.status, w.Code)
+		}
+	}
+}
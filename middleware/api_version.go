@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	// APIVersionCtxKey is the context.Context key to store the API version
+	// requested by a client.
+	APIVersionCtxKey = &contextKey{"APIVersion"}
+
+	// apiVersionRe extracts the version of a vendor media type, such as
+	// application/vnd.myapp.v2+json or application/vnd.myapp+json;version=2.
+	apiVersionRe = regexp.MustCompile(`(?i)vnd\.[^\s,;]*?\.v(\d+(?:\.\d+)*)|;\s*version="?v?(\d+(?:\.\d+)*)`)
+)
+
+// APIVersion is a middleware that stores the API version requested by a
+// client in the request context, where handlers can read it with
+// GetAPIVersion and branch on it. The version is read from the custom header,
+// if given and set, ie. "X-API-Version: 2", and otherwise from a vendor media
+// type of the Accept header, ie. "application/vnd.myapp.v2+json". Requests
+// without a version get defaultVersion.
+//
+// Versions are stored without their "v" prefix, ie. "2" or "2.1".
+//
+//	r.Use(middleware.APIVersion("X-API-Version", "1"))
+func APIVersion(header, defaultVersion string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			version := defaultVersion
+			if v := apiVersion(r, header); v != "" {
+				version = v
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), APIVersionCtxKey, version))
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// apiVersion returns the API version of a request, or the empty string if it
+// doesn't carry one.
+func apiVersion(r *http.Request, header string) string {
+	if header != "" {
+		if v := strings.TrimSpace(r.Header.Get(header)); v != "" {
+			return strings.TrimPrefix(strings.TrimPrefix(v, "v"), "V")
+		}
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		if m := apiVersionRe.FindStringSubmatch(accept); m != nil {
+			if m[1] != "" {
+				return m[1]
+			}
+			return m[2]
+		}
+	}
+	return ""
+}
+
+// GetAPIVersion returns the API version stored by the APIVersion middleware,
+// or the empty string if there is none.
+func GetAPIVersion(ctx context.Context) string {
+	version, _ := ctx.Value(APIVersionCtxKey).(string)
+	return version
+}
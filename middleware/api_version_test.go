@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIVersion(t *testing.T) {
+	h := APIVersion("X-API-Version", "1")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(GetAPIVersion(r.Context())))
+	}))
+
+	tests := []struct {
+		accept   string
+		header   string
+		expected string
+	}{
+		{"application/vnd.myapp.v2+json", "", "2"},
+		{"application/vnd.myapp.v2.1+json", "", "2.1"},
+		{"application/vnd.github.myapp.V3+json", "", "3"},
+		{"text/html, application/vnd.myapp.v4+json;q=0.9", "", "4"},
+		{"application/vnd.myapp+json; version=5", "", "5"},
+		{"application/json", "", "1"},
+		{"", "", "1"},
+		{"application/vnd.myapp.v2+json", "v7", "7"},
+		{"", "8", "8"},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tc.accept != "" {
+			r.Header.Set("Accept", tc.accept)
+		}
+		if tc.header != "" {
+			r.Header.Set("X-API-Version", tc.header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Body.String() != tc.expected {
+			t.Fatalf("%q %q: expected version %q, got %q", tc.accept, tc.header, tc.expected, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/goclover/clover"
+)
+
+// WithBag is a middleware that stores a new clover.Bag in the request context,
+// where the following middlewares and the handler share values through
+// clover.BagFrom.
+func WithBag(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(clover.WithBag(r.Context())))
+	}
+	return http.HandlerFunc(fn)
+}
@@ -0,0 +1,279 @@
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goclover/clover"
+)
+
+var (
+	defaultCacheMaxEntries = 1024
+)
+
+// CacheOpts represents a set of response caching options.
+type CacheOpts struct {
+	// TTL is how long a cached response is served for.
+	TTL time.Duration
+
+	// KeyFunc returns the cache key of a request. Defaults to the request
+	// host and URI.
+	KeyFunc func(r *http.Request) string
+
+	// MaxEntries is the number of responses kept in the cache, after which
+	// the least recently used are evicted. Defaults to 1024.
+	MaxEntries int
+
+	// StaleWhileRevalidate is how long an expired response is still served,
+	// with a `X-Cache: STALE` header, while it's refreshed in the background,
+	// sparing clients the latency of the handler. A single refresh runs at a
+	// time per key. Expired responses aren't served when zero.
+	StaleWhileRevalidate time.Duration
+}
+
+// Cache is a middleware that caches successful GET responses in memory for
+// the given ttl, keyed by keyFn (or the request host and URI if nil). Responses served
+// from the cache carry a `X-Cache: HIT` header, while the others carry a
+// `X-Cache: MISS` header.
+//
+// Only 200 responses are cached. Responses with a `Cache-Control: no-store`
+// or `Cache-Control: private` header, or setting a cookie, are never cached,
+// as they're meant for a single client.
+func Cache(ttl time.Duration, keyFn func(r *http.Request) string) func(http.Handler) http.Handler {
+	return CacheWithOpts(CacheOpts{TTL: ttl, KeyFunc: keyFn})
+}
+
+// CacheWithOpts is a middleware that caches GET responses using passed CacheOpts.
+func CacheWithOpts(opts CacheOpts) func(http.Handler) http.Handler {
+	if opts.TTL <= 0 {
+		panic("clover/middleware: Cache expects ttl > 0")
+	}
+	if opts.KeyFunc == nil {
+		opts.KeyFunc = func(r *http.Request) string { return r.Host + r.URL.RequestURI() }
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = defaultCacheMaxEntries
+	}
+
+	c := &responseCache{
+		maxEntries: opts.MaxEntries,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+		refreshing: map[string]struct{}{},
+	}
+
+	// store caches the response of a request, as long as it's cacheable,
+	// with the headers set by the handler only
+	store := func(key string, status int, header http.Header, body []byte) {
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status != http.StatusOK || len(header.Values("Set-Cookie")) > 0 {
+			return
+		}
+		if cacheControlHas(header, "no-store") || cacheControlHas(header, "private") {
+			return
+		}
+		header = header.Clone()
+		header.Del("X-Cache")
+		c.add(key, &cachedResponse{
+			status:  status,
+			header:  header,
+			body:    body,
+			expires: time.Now().Add(opts.TTL),
+			stale:   time.Now().Add(opts.TTL + opts.StaleWhileRevalidate),
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := opts.KeyFunc(r)
+			if res, stale := c.get(key); res != nil {
+				if !stale {
+					res.writeTo(w, "HIT")
+					return
+				}
+				if c.startRefresh(key) {
+					r2 := detachRequest(r)
+					go func() {
+						defer c.endRefresh(key)
+						// the refresh runs past the request's Recoverer
+						defer func() {
+							if rvr := recover(); rvr != nil {
+								PrintPrettyStack(rvr)
+							}
+						}()
+						rw := &refreshWriter{header: http.Header{}}
+						next.ServeHTTP(rw, r2)
+						store(key, rw.status, rw.header, rw.body.Bytes())
+					}()
+				}
+				res.writeTo(w, "STALE")
+				return
+			}
+
+			w.Header().Set("X-Cache", "MISS")
+			before := w.Header().Clone()
+			ww := NewWrapResponseWriter(w, r.ProtoMajor)
+			buf := &bytes.Buffer{}
+			ww.Tee(buf)
+
+			next.ServeHTTP(ww, r)
+
+			store(key, ww.Status(), handlerHeaders(before, ww.Header()), buf.Bytes())
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// cacheControlHas reports whether the Cache-Control header has the directive.
+func cacheControlHas(header http.Header, directive string) bool {
+	for _, v := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			d, _, _ = strings.Cut(d, "=")
+			if strings.EqualFold(strings.TrimSpace(d), directive) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// detachRequest returns a copy of r for a handler running once the response
+// to r has been written, with a context that isn't canceled along with r,
+// and a routing context of its own, as the one of r is reused by the router
+// for another request.
+func detachRequest(r *http.Request) *http.Request {
+	ctx := withoutCancel(r.Context())
+	if rctx := clover.RouteContext(ctx); rctx != nil {
+		rc := clover.NewRouteContext()
+		rc.Routes = rctx.Routes
+		rc.RoutePath = rctx.RoutePath
+		rc.RouteMethod = rctx.RouteMethod
+		rc.RoutePatterns = append([]string(nil), rctx.RoutePatterns...)
+		rc.URLParams.Keys = append([]string(nil), rctx.URLParams.Keys...)
+		rc.URLParams.Values = append([]string(nil), rctx.URLParams.Values...)
+		ctx = context.WithValue(ctx, clover.RouteCtxKey, rc)
+	}
+	return r.Clone(ctx)
+}
+
+// refreshWriter records the response of a background refresh.
+type refreshWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *refreshWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *refreshWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+}
+
+func (rw *refreshWriter) Write(p []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	return rw.body.Write(p)
+}
+
+// cachedResponse is a response captured by the Cache middleware.
+type cachedResponse struct {
+	key     string
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+	stale   time.Time
+}
+
+func (res *cachedResponse) writeTo(w http.ResponseWriter, xcache string) {
+	for k, vs := range res.header {
+		w.Header()[k] = append([]string(nil), vs...)
+	}
+	w.Header().Set("X-Cache", xcache)
+	w.WriteHeader(res.status)
+	w.Write(res.body)
+}
+
+// responseCache is a LRU cache of responses.
+type responseCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+
+	// keys being refreshed in the background
+	refreshing map[string]struct{}
+}
+
+// get returns the response cached under key, and whether it has expired but
+// may still be served while it's refreshed.
+func (c *responseCache) get(key string) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	res := e.Value.(*cachedResponse)
+	now := time.Now()
+	if now.After(res.stale) {
+		c.ll.Remove(e)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return res, now.After(res.expires)
+}
+
+// startRefresh reports whether the caller should refresh key, which is the
+// case unless it's already being refreshed.
+func (c *responseCache) startRefresh(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.refreshing[key]; ok {
+		return false
+	}
+	c.refreshing[key] = struct{}{}
+	return true
+}
+
+func (c *responseCache) endRefresh(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.refreshing, key)
+}
+
+func (c *responseCache) add(key string, res *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res.key = key
+	if e, ok := c.items[key]; ok {
+		e.Value = res
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(res)
+	if c.ll.Len() > c.maxEntries {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*cachedResponse).key)
+	}
+}
@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goclover/clover"
+)
+
+func TestCache(t *testing.T) {
+	var hits int32
+
+	r := clover.New()
+	r.Use(Cache(100*time.Millisecond, nil))
+	r.MethodFunc("GET", "/count", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(fmt.Sprintf("count %d", n)))
+	})
+	r.MethodFunc("GET", "/nostore", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte(fmt.Sprintf("count %d", n)))
+	})
+	r.MethodFunc("GET", "/missing", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, body := testRequest(t, ts, "GET", "/count", nil)
+	assertEqual(t, "MISS", res.Header.Get("X-Cache"))
+	assertEqual(t, "count 1", body)
+
+	res, body = testRequest(t, ts, "GET", "/count", nil)
+	assertEqual(t, "HIT", res.Header.Get("X-Cache"))
+	assertEqual(t, "text/plain", res.Header.Get("Content-Type"))
+	assertEqual(t, "count 1", body)
+
+	res, body = testRequest(t, ts, "GET", "/count?page=2", nil)
+	assertEqual(t, "MISS", res.Header.Get("X-Cache"))
+	assertEqual(t, "count 2", body)
+
+	// expired entries are refreshed
+	time.Sleep(150 * time.Millisecond)
+	res, body = testRequest(t, ts, "GET", "/count", nil)
+	assertEqual(t, "MISS", res.Header.Get("X-Cache"))
+	assertEqual(t, "count 3", body)
+
+	testRequest(t, ts, "GET", "/nostore", nil)
+	res, body = testRequest(t, ts, "GET", "/nostore", nil)
+	assertEqual(t, "MISS", res.Header.Get("X-Cache"))
+	assertEqual(t, "count 5", body)
+
+	testRequest(t, ts, "GET", "/missing", nil)
+	res, _ = testRequest(t, ts, "GET", "/missing", nil)
+	assertEqual(t, "MISS", res.Header.Get("X-Cache"))
+	assertEqual(t, int32(7), atomic.LoadInt32(&hits))
+}
+
+func TestCachePrivateResponses(t *testing.T) {
+	var hits int32
+
+	r := clover.New()
+	r.Use(Cache(time.Minute, nil))
+	r.MethodFunc("GET", "/login", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: fmt.Sprint(n)})
+		w.Write([]byte("welcome"))
+	})
+	r.MethodFunc("GET", "/me", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "Private, max-age=60")
+		w.Write([]byte(fmt.Sprintf("me %d", n)))
+	})
+	r.MethodFunc("GET", "/host", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	testRequest(t, ts, "GET", "/login", nil)
+	res, _ := testRequest(t, ts, "GET", "/login", nil)
+	assertEqual(t, "MISS", res.Header.Get("X-Cache"))
+	assertEqual(t, "session=2", res.Header.Get("Set-Cookie"))
+
+	testRequest(t, ts, "GET", "/me", nil)
+	res, body := testRequest(t, ts, "GET", "/me", nil)
+	assertEqual(t, "MISS", res.Header.Get("X-Cache"))
+	assertEqual(t, "me 4", body)
+
+	// virtual hosts don't share entries
+	for _, host := range []string{"a.example.com", "b.example.com", "a.example.com"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "http://"+host+"/host", nil))
+		assertEqual(t, host, w.Body.String())
+	}
+}
+
+func TestCacheOuterHeaders(t *testing.T) {
+	var reqs int32
+
+	r := clover.New()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-Id", fmt.Sprint(atomic.AddInt32(&reqs, 1)))
+			w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Use(Cache(time.Minute, nil))
+	r.MethodFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("ok"))
+	})
+
+	for i, origin := range []string{"https://a.example.com", "https://b.example.com"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Origin", origin)
+		r.ServeHTTP(w, req)
+
+		assertEqual(t, []string{"MISS", "HIT"}[i], w.Header().Get("X-Cache"))
+		assertEqual(t, fmt.Sprint(i+1), w.Header().Get("X-Request-Id"))
+		assertEqual(t, origin, w.Header().Get("Access-Control-Allow-Origin"))
+		assertEqual(t, "text/plain", w.Header().Get("Content-Type"))
+		assertEqual(t, 1, len(w.Header().Values("X-Cache")))
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	var hits int32
+
+	r := clover.New()
+	r.Use(CacheWithOpts(CacheOpts{TTL: time.Minute, MaxEntries: 1}))
+	r.MethodFunc("GET", "/{id}", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(clover.URLParam(r, "id")))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	testRequest(t, ts, "GET", "/a", nil)
+	testRequest(t, ts, "GET", "/b", nil)
+	res, body := testRequest(t, ts, "GET", "/a", nil)
+	assertEqual(t, "MISS", res.Header.Get("X-Cache"))
+	assertEqual(t, "a", body)
+	assertEqual(t, int32(3), atomic.LoadInt32(&hits))
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+
+	r := clover.New()
+	r.Use(CacheWithOpts(CacheOpts{TTL: 50 * time.Millisecond, StaleWhileRevalidate: time.Second}))
+	r.MethodFunc("GET", "/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		if n > 1 {
+			<-release
+		}
+		w.Write([]byte(fmt.Sprintf("item %s v%d", clover.URLParam(r, "id"), n)))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, body := testRequest(t, ts, "GET", "/items/7", nil)
+	assertEqual(t, "MISS", res.Header.Get("X-Cache"))
+	assertEqual(t, "item 7 v1", body)
+
+	// the stale response is served right away, while a single refresh runs
+	time.Sleep(100 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		res, body = testRequest(t, ts, "GET", "/items/7", nil)
+		assertEqual(t, "STALE", res.Header.Get("X-Cache"))
+		assertEqual(t, "item 7 v1", body)
+	}
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		res, body = testRequest(t, ts, "GET", "/items/7", nil)
+		if res.Header.Get("X-Cache") == "HIT" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	assertEqual(t, "HIT", res.Header.Get("X-Cache"))
+	assertEqual(t, "item 7 v2", body)
+	assertEqual(t, int32(2), atomic.LoadInt32(&hits))
+
+	// past the staleness window, the response isn't served anymore
+	time.Sleep(1100 * time.Millisecond)
+	res, body = testRequest(t, ts, "GET", "/items/7", nil)
+	assertEqual(t, "MISS", res.Header.Get("X-Cache"))
+	assertEqual(t, "item 7 v3", body)
+}
+
+func TestCacheStaleRefreshPanic(t *testing.T) {
+	oldRecovererErrorWriter := RecovererErrorWriter
+	defer func() { RecovererErrorWriter = oldRecovererErrorWriter }()
+	logged := make(chan struct{}, 1)
+	RecovererErrorWriter = writerFunc(func(p []byte) (int, error) {
+		select {
+		case logged <- struct{}{}:
+		default:
+		}
+		return len(p), nil
+	})
+
+	var hits int32
+
+	r := clover.New()
+	r.Use(CacheWithOpts(CacheOpts{TTL: 50 * time.Millisecond, StaleWhileRevalidate: time.Minute}))
+	r.MethodFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 2 {
+			panic("refresh failed")
+		}
+		w.Write([]byte("ok"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	testRequest(t, ts, "GET", "/", nil)
+	time.Sleep(100 * time.Millisecond)
+	res, body := testRequest(t, ts, "GET", "/", nil)
+	assertEqual(t, "STALE", res.Header.Get("X-Cache"))
+	assertEqual(t, "ok", body)
+
+	select {
+	case <-logged:
+	case <-time.After(time.Second):
+		t.Fatal("expected the refresh panic to be logged")
+	}
+
+	// the refresh is released, so the next stale hit starts another one
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&hits) < 3 && time.Now().Before(deadline) {
+		testRequest(t, ts, "GET", "/", nil)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&hits); n < 3 {
+		t.Fatalf("expected another refresh after the panic, got %d handler calls", n)
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
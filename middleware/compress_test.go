@@ -3,15 +3,19 @@ package middleware
 import (
 	"compress/flate"
 	"compress/gzip"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/goclover/clover"
+	"github.com/goclover/clover/render"
 )
 
 func TestCompressor(t *testing.T) {
@@ -217,3 +221,34 @@ func decodeResponseBody(t *testing.T, resp *http.Response) string {
 
 	return string(respBody)
 }
+
+func TestCompressRenderContentLength(t *testing.T) {
+	r := clover.New()
+	r.Use(Compress(5, "application/json"))
+
+	data := map[string]string{"message": strings.Repeat("clover ", 100)}
+	r.Handle("/json", func(ctx context.Context, r *http.Request) render.Render {
+		return render.JSON(data)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, body := testRequestWithAcceptedEncodings(t, ts, "GET", "/json", "gzip")
+	if enc := resp.Header.Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", enc)
+	}
+
+	var got map[string]string
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("failed to decode compressed body: %v", err)
+	}
+	if got["message"] != data["message"] {
+		t.Fatalf("unexpected decoded body: %q", body)
+	}
+
+	resp, body = testRequestWithAcceptedEncodings(t, ts, "GET", "/json", "identity")
+	if cl := resp.Header.Get("Content-Length"); cl != strconv.Itoa(len(body)) {
+		t.Fatalf("expected Content-Length %d, got %q", len(body), cl)
+	}
+}
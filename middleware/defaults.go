@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/goclover/clover"
+)
+
+// DefaultsOption configures the middleware stack of NewRouterWithDefaults.
+type DefaultsOption func(*defaultsConfig)
+
+type defaultsConfig struct {
+	stack []func(http.Handler) http.Handler
+	extra []func(http.Handler) http.Handler
+}
+
+// WithoutRealIP leaves out the RealIP middleware, for services that aren't
+// behind a trusted reverse proxy.
+func WithoutRealIP() DefaultsOption {
+	return withoutDefault(RealIP)
+}
+
+// WithoutLogger leaves out the Logger middleware.
+func WithoutLogger() DefaultsOption {
+	return withoutDefault(Logger)
+}
+
+// WithMiddlewares appends middlewares after the default stack.
+func WithMiddlewares(middlewares ...func(http.Handler) http.Handler) DefaultsOption {
+	return func(c *defaultsConfig) {
+		c.extra = append(c.extra, middlewares...)
+	}
+}
+
+func withoutDefault(mw func(http.Handler) http.Handler) DefaultsOption {
+	name := funcName(mw)
+	return func(c *defaultsConfig) {
+		stack := c.stack[:0]
+		for _, m := range c.stack {
+			if funcName(m) != name {
+				stack = append(stack, m)
+			}
+		}
+		c.stack = stack
+	}
+}
+
+// NewRouterWithDefaults returns a new Mux loaded with a sane default middleware
+// stack, made of RequestID, RealIP, Logger and Recoverer in that order. Use
+// clover.NewRouter() for a bare Mux.
+func NewRouterWithDefaults(opts ...DefaultsOption) *clover.Mux {
+	c := &defaultsConfig{
+		stack: []func(http.Handler) http.Handler{RequestID, RealIP, Logger, Recoverer},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r := clover.NewRouter()
+	r.Use(c.stack...)
+	r.Use(c.extra...)
+	return r
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewRouterWithDefaults(t *testing.T) {
+	names := func(mws []func(http.Handler) http.Handler) []string {
+		var s []string
+		for _, mw := range mws {
+			s = append(s, funcName(mw))
+		}
+		return s
+	}
+	const pkg = "github.com/goclover/clover/middleware."
+
+	r := NewRouterWithDefaults()
+	assertEqual(t, []string{pkg + "RequestID", pkg + "RealIP", pkg + "Logger", pkg + "Recoverer"}, names(r.Middlewares()))
+
+	r.MethodFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+		if GetReqID(r.Context()) == "" || GetLogEntry(r) == nil {
+			w.WriteHeader(http.StatusExpectationFailed)
+		}
+		w.Write([]byte(r.RemoteAddr))
+	})
+	r.MethodFunc("GET", "/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/", nil)
+	req.Header.Set("X-Real-IP", "100.100.100.100")
+	res, err := http.DefaultClient.Do(req)
+	assertNoError(t, err)
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assertEqual(t, http.StatusOK, res.StatusCode)
+	assertEqual(t, "100.100.100.100", string(body))
+
+	oldRecovererErrorWriter := RecovererErrorWriter
+	defer func() { RecovererErrorWriter = oldRecovererErrorWriter }()
+	RecovererErrorWriter = io.Discard
+
+	res, _ = testRequest(t, ts, "GET", "/panic", nil)
+	assertEqual(t, http.StatusInternalServerError, res.StatusCode)
+
+	r = NewRouterWithDefaults(WithoutRealIP(), WithoutLogger(), WithMiddlewares(NoCache))
+	assertEqual(t, []string{pkg + "RequestID", pkg + "Recoverer", pkg + "NoCache"}, names(r.Middlewares()))
+}
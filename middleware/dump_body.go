@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DumpBody is a debugging middleware that writes each request and its
+// response, headers and bodies included, to w. Bodies are capped at 64KB and
+// the values of the Authorization, Cookie, Set-Cookie, Proxy-Authorization
+// and X-Api-Key headers are redacted. It buffers the bodies, so it's only
+// meant for a debug subrouter or a staging environment.
+//
+//	r.Route("/webhooks", func(r clover.Router) {
+//		r.Use(middleware.DumpBody(os.Stderr))
+//		...
+//	})
+func DumpBody(w io.Writer) func(next http.Handler) http.Handler {
+	return DumpBodyWithOpts(DumpBodyOpts{Writer: w})
+}
+
+// DumpBodyOpts represents a set of DumpBody options.
+type DumpBodyOpts struct {
+	// Writer receives the dumps, one Write call per request.
+	Writer io.Writer
+
+	// MaxBytes caps the bytes of each body that are dumped, 64KB if zero.
+	MaxBytes int
+
+	// RedactHeaders are the headers whose values are redacted, the
+	// DumpBody defaults if nil.
+	RedactHeaders []string
+}
+
+var defaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// DumpBodyWithOpts is a debugging middleware that dumps requests and their
+// responses using passed DumpBodyOpts, see DumpBody.
+func DumpBodyWithOpts(opts DumpBodyOpts) func(next http.Handler) http.Handler {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 64 << 10
+	}
+	if opts.RedactHeaders == nil {
+		opts.RedactHeaders = defaultRedactHeaders
+	}
+	redact := make(map[string]struct{}, len(opts.RedactHeaders))
+	for _, name := range opts.RedactHeaders {
+		redact[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	var mu sync.Mutex
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			// read the start of the request body, and give it back whole to
+			// the handler
+			reqBody, _ := peekBody(r, int64(opts.MaxBytes))
+
+			resBody := &cappedBuffer{max: opts.MaxBytes}
+			ww := NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(resBody)
+			next.ServeHTTP(ww, r)
+
+			var dump bytes.Buffer
+			fmt.Fprintf(&dump, "> %s %s %s\n", r.Method, r.RequestURI, r.Proto)
+			dumpHeaders(&dump, "> ", r.Header, redact)
+			dumpBody(&dump, "> ", reqBody, len(reqBody) > opts.MaxBytes, opts.MaxBytes)
+			fmt.Fprintf(&dump, "< %d %s\n", ww.Status(), http.StatusText(ww.Status()))
+			dumpHeaders(&dump, "< ", ww.Header(), redact)
+			dumpBody(&dump, "< ", resBody.Bytes(), resBody.truncated, opts.MaxBytes)
+
+			mu.Lock()
+			opts.Writer.Write(dump.Bytes())
+			mu.Unlock()
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func dumpHeaders(dump *bytes.Buffer, prefix string, h http.Header, redact map[string]struct{}) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range h[name] {
+			if _, ok := redact[http.CanonicalHeaderKey(name)]; ok {
+				v = "[REDACTED]"
+			}
+			fmt.Fprintf(dump, "%s%s: %s\n", prefix, name, v)
+		}
+	}
+}
+
+func dumpBody(dump *bytes.Buffer, prefix string, body []byte, truncated bool, maxBytes int) {
+	if len(body) == 0 {
+		return
+	}
+	if len(body) > maxBytes {
+		body = body[:maxBytes]
+	}
+	dump.WriteString(strings.TrimSpace(prefix) + "\n")
+	for _, line := range strings.Split(string(body), "\n") {
+		dump.WriteString(prefix + line + "\n")
+	}
+	if truncated {
+		dump.WriteString(prefix + "... (truncated)\n")
+	}
+}
+
+// cappedBuffer keeps the first max bytes written to it, and discards the
+// rest.
+type cappedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if n := b.max - b.Len(); n < len(p) {
+		b.truncated = true
+		if n > 0 {
+			b.Buffer.Write(p[:n])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDumpBody(t *testing.T) {
+	var out bytes.Buffer
+	h := DumpBody(&out)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"echo":` + string(body) + `}`))
+	}))
+
+	req := httptest.NewRequest("POST", "/hooks?x=1", strings.NewReader(`{"id":1}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if w.Body.String() != `{"echo":{"id":1}}` {
+		t.Fatalf("expected the handler to see the request body, got %q", w.Body.String())
+	}
+	expected := `> POST /hooks?x=1 HTTP/1.1
+> Authorization: [REDACTED]
+> Content-Type: application/json
+>
+> {"id":1}
+< 201 Created
+< Content-Type: application/json
+< Set-Cookie: [REDACTED]
+<
+< {"echo":{"id":1}}
+`
+	if out.String() != expected {
+		t.Fatalf("unexpected dump:\n%s", out.String())
+	}
+
+	// bodies are capped
+	out.Reset()
+	h = DumpBodyWithOpts(DumpBodyOpts{Writer: &out, MaxBytes: 4})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PUT", "/", strings.NewReader("0123456789")))
+	if w.Body.String() != "0123456789" {
+		t.Fatalf("expected the whole body to be echoed, got %q", w.Body.String())
+	}
+	if strings.Count(out.String(), "0123\n") != 2 || strings.Count(out.String(), "... (truncated)") != 2 {
+		t.Fatalf("expected both bodies to be truncated:\n%s", out.String())
+	}
+}
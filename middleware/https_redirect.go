@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/goclover/clover"
+)
+
+// HTTPSRedirectOpts represents a set of HTTPSRedirect options.
+type HTTPSRedirectOpts struct {
+	// Status is the redirect status code, 301 Moved Permanently if zero.
+	// Use 308 Permanent Redirect to preserve the method and body of
+	// non-GET requests.
+	Status int
+
+	// Host replaces the host of the request in the redirect URL if set, ie.
+	// "www.example.com" or "example.com:8443".
+	Host string
+
+	// ExemptPaths are served over plain HTTP, ie. load balancer health
+	// checks.
+	ExemptPaths []string
+}
+
+// HTTPSRedirect is a middleware that redirects the requests received over
+// plain HTTP to their https:// equivalent URL. Requests are detected as plain
+// HTTP as in clover.Request.Scheme, so set clover.TrustForwardedProto to
+// honor the X-Forwarded-Proto header of a TLS terminating proxy.
+func HTTPSRedirect(opts HTTPSRedirectOpts) func(next http.Handler) http.Handler {
+	if opts.Status == 0 {
+		opts.Status = http.StatusMovedPermanently
+	}
+	exempt := make(map[string]struct{}, len(opts.ExemptPaths))
+	for _, path := range opts.ExemptPaths {
+		exempt[path] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := exempt[r.URL.Path]; ok || clover.NewRequest(r).Scheme() == "https" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			host := r.Host
+			if opts.Host != "" {
+				host = opts.Host
+			}
+			u := *r.URL
+			u.Scheme = "https"
+			u.Host = host
+			http.Redirect(w, r, u.String(), opts.Status)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
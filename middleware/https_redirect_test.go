@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goclover/clover"
+)
+
+func TestHTTPSRedirect(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	h := HTTPSRedirect(HTTPSRedirectOpts{ExemptPaths: []string{"/healthz"}})(ok)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/users?page=2", nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "https://example.com/users?page=2" {
+		t.Fatalf("unexpected redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "https://example.com/users", nil))
+	if w.Code != 200 || w.Body.String() != "ok" {
+		t.Fatalf("expected the https request to pass, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/healthz", nil))
+	if w.Code != 200 {
+		t.Fatalf("expected the health check to pass, got %d", w.Code)
+	}
+
+	defer func(trust bool) { clover.TrustForwardedProto = trust }(clover.TrustForwardedProto)
+	clover.TrustForwardedProto = true
+	r := httptest.NewRequest("GET", "http://example.com/users", nil)
+	r.Header.Set("X-Forwarded-Proto", "https")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("expected the proxied https request to pass, got %d", w.Code)
+	}
+
+	h = HTTPSRedirect(HTTPSRedirectOpts{Status: http.StatusPermanentRedirect, Host: "www.example.com"})(ok)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "http://example.com:8080/orders", nil))
+	if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != "https://www.example.com/orders" {
+		t.Fatalf("unexpected redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
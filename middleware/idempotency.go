@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the name of the HTTP Header which carries the
+// idempotency key of a request.
+var IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentResponse is a response recorded by the Idempotency middleware.
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyStore keeps track of the requests handled by the Idempotency
+// middleware. Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Start reserves key for the current request. It returns the recorded
+	// response if a request with the same key already completed, or
+	// inFlight if one is still being processed.
+	Start(key string) (res *IdempotentResponse, inFlight bool, err error)
+
+	// Finish records the response of the request which reserved key.
+	Finish(key string, res *IdempotentResponse) error
+
+	// Cancel releases key without recording a response, so the request
+	// can be retried.
+	Cancel(key string) error
+}
+
+// Idempotency is a middleware that makes unsafe requests (POST, PUT, PATCH,
+// DELETE...) carrying an `Idempotency-Key` header safe to retry. The first
+// response for a key is recorded in the store and replayed for the retries
+// with the same key, with a `Idempotent-Replayed: true` header, preventing
+// duplicate side effects. While the first request is still in flight, the
+// retries are rejected with a 409 Conflict.
+//
+// Keys are scoped to the method and path of the request, but not to the
+// client: use IdempotencyWithOpts with a KeyFunc to scope them to the
+// authenticated user. Server errors (5xx) are not recorded, so the request
+// can be retried.
+func Idempotency(store IdempotencyStore) func(http.Handler) http.Handler {
+	return IdempotencyWithOpts(IdempotencyOpts{Store: store})
+}
+
+// IdempotencyOpts represents a set of Idempotency options.
+type IdempotencyOpts struct {
+	// Store keeps track of the requests and their recorded responses.
+	Store IdempotencyStore
+
+	// KeyFunc returns the store key of a request carrying the idempotency
+	// key ikey, or "" to handle the request as if it had none. Defaults to
+	// the method, path and ikey. Scoping the key to the client, ie.
+	//
+	//  KeyFunc: func(r *http.Request, ikey string) string {
+	//    user, _, _ := r.BasicAuth()
+	//    return user + " " + r.Method + " " + r.URL.Path + " " + ikey
+	//  },
+	//
+	// prevents a client from being replayed the response of another one
+	// reusing the same idempotency key.
+	KeyFunc func(r *http.Request, ikey string) string
+}
+
+// IdempotencyWithOpts is a middleware making unsafe requests safe to retry
+// using passed IdempotencyOpts.
+func IdempotencyWithOpts(opts IdempotencyOpts) func(http.Handler) http.Handler {
+	if opts.Store == nil {
+		panic("clover/middleware: Idempotency expects a store")
+	}
+	if opts.KeyFunc == nil {
+		opts.KeyFunc = func(r *http.Request, ikey string) string {
+			return r.Method + " " + r.URL.Path + " " + ikey
+		}
+	}
+	store := opts.Store
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ikey := r.Header.Get(IdempotencyKeyHeader)
+			if ikey == "" || isSafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := opts.KeyFunc(r, ikey)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, inFlight, err := store.Start(key)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			if inFlight {
+				http.Error(w, "A request with the same idempotency key is in progress.", http.StatusConflict)
+				return
+			}
+			if res != nil {
+				for k, vs := range res.Header {
+					w.Header()[k] = append([]string(nil), vs...)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(res.Status)
+				w.Write(res.Body)
+				return
+			}
+
+			before := w.Header().Clone()
+			ww := NewWrapResponseWriter(w, r.ProtoMajor)
+			buf := &bytes.Buffer{}
+			ww.Tee(buf)
+
+			finished := false
+			defer func() {
+				if !finished {
+					store.Cancel(key)
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError {
+				return
+			}
+			finished = true
+			store.Finish(key, &IdempotentResponse{Status: status, Header: handlerHeaders(before, ww.Header()), Body: buf.Bytes()})
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// NewIdempotencyMemoryStore returns an in-memory IdempotencyStore, which
+// forgets the recorded responses after ttl. Expired responses are swept at
+// most once per ttl, as new requests come in.
+func NewIdempotencyMemoryStore(ttl time.Duration) IdempotencyStore {
+	return &idempotencyMemoryStore{ttl: ttl, entries: map[string]*idempotencyEntry{}, swept: time.Now()}
+}
+
+type idempotencyEntry struct {
+	res     *IdempotentResponse
+	expires time.Time
+}
+
+type idempotencyMemoryStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*idempotencyEntry
+	swept   time.Time
+}
+
+func (s *idempotencyMemoryStore) Start(key string) (*IdempotentResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := time.Now(); now.Sub(s.swept) >= s.ttl {
+		s.sweep(now)
+	}
+
+	if e, ok := s.entries[key]; ok {
+		if e.res == nil {
+			return nil, true, nil
+		}
+		if time.Now().Before(e.expires) {
+			return e.res, false, nil
+		}
+	}
+	s.entries[key] = &idempotencyEntry{}
+	return nil, false, nil
+}
+
+// sweep removes the expired responses, leaving the in-flight requests.
+func (s *idempotencyMemoryStore) sweep(now time.Time) {
+	for key, e := range s.entries {
+		if e.res != nil && !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+	s.swept = now
+}
+
+func (s *idempotencyMemoryStore) Finish(key string, res *IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = &idempotencyEntry{res: res, expires: time.Now().Add(s.ttl)}
+	return nil
+}
+
+func (s *idempotencyMemoryStore) Cancel(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goclover/clover"
+)
+
+func idempotentRequest(ts *httptest.Server, key, user string) (*http.Response, string, error) {
+	req, _ := http.NewRequest("POST", ts.URL+"/charges", nil)
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if user != "" {
+		req.SetBasicAuth(user, "secret")
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return res, string(body), err
+}
+
+func testIdempotentRequest(t *testing.T, ts *httptest.Server, key string) (*http.Response, string) {
+	res, body, err := idempotentRequest(ts, key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, body
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	var charges int32
+
+	r := clover.New()
+	r.Use(Idempotency(NewIdempotencyMemoryStore(time.Minute)))
+	r.MethodFunc("POST", "/charges", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&charges, 1)
+		w.Header().Set("X-Charge", fmt.Sprint(n))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(fmt.Sprintf("charge %d", n)))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, body := testIdempotentRequest(t, ts, "abc")
+	assertEqual(t, http.StatusCreated, res.StatusCode)
+	assertEqual(t, "charge 1", body)
+	assertEqual(t, "", res.Header.Get("Idempotent-Replayed"))
+
+	res, body = testIdempotentRequest(t, ts, "abc")
+	assertEqual(t, http.StatusCreated, res.StatusCode)
+	assertEqual(t, "charge 1", body)
+	assertEqual(t, "1", res.Header.Get("X-Charge"))
+	assertEqual(t, "true", res.Header.Get("Idempotent-Replayed"))
+
+	_, body = testIdempotentRequest(t, ts, "def")
+	assertEqual(t, "charge 2", body)
+
+	_, body = testIdempotentRequest(t, ts, "")
+	assertEqual(t, "charge 3", body)
+	assertEqual(t, int32(3), atomic.LoadInt32(&charges))
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	r := clover.New()
+	r.Use(Idempotency(NewIdempotencyMemoryStore(time.Minute)))
+	r.MethodFunc("POST", "/charges", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("charged"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	done := make(chan string)
+	go func() {
+		_, body, err := idempotentRequest(ts, "abc", "")
+		if err != nil {
+			t.Error(err)
+		}
+		done <- body
+	}()
+
+	<-started
+	res, _ := testIdempotentRequest(t, ts, "abc")
+	assertEqual(t, http.StatusConflict, res.StatusCode)
+
+	close(release)
+	assertEqual(t, "charged", <-done)
+
+	res, body := testIdempotentRequest(t, ts, "abc")
+	assertEqual(t, http.StatusOK, res.StatusCode)
+	assertEqual(t, "charged", body)
+}
+
+func TestIdempotencyKeyFunc(t *testing.T) {
+	var charges int32
+
+	r := clover.New()
+	r.Use(IdempotencyWithOpts(IdempotencyOpts{
+		Store: NewIdempotencyMemoryStore(time.Minute),
+		KeyFunc: func(r *http.Request, ikey string) string {
+			user, _, _ := r.BasicAuth()
+			return user + " " + r.Method + " " + r.URL.Path + " " + ikey
+		},
+	}))
+	r.MethodFunc("POST", "/charges", func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		w.Write([]byte(fmt.Sprintf("%s charge %d", user, atomic.AddInt32(&charges, 1))))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	for _, tc := range []struct{ user, expected string }{
+		{"alice", "alice charge 1"},
+		{"bob", "bob charge 2"},
+		{"alice", "alice charge 1"},
+	} {
+		_, body, err := idempotentRequest(ts, "abc", tc.user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, tc.expected, body)
+	}
+}
+
+func TestIdempotencyMemoryStoreSweep(t *testing.T) {
+	s := NewIdempotencyMemoryStore(50 * time.Millisecond).(*idempotencyMemoryStore)
+
+	s.Start("done")
+	s.Finish("done", &IdempotentResponse{Status: http.StatusOK})
+	s.Start("pending")
+
+	time.Sleep(100 * time.Millisecond)
+	s.Start("new")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries["done"]; ok {
+		t.Fatal("expected the expired response to be swept")
+	}
+	if _, ok := s.entries["pending"]; !ok {
+		t.Fatal("expected the in-flight request to be kept")
+	}
+	if len(s.entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(s.entries))
+	}
+}
+
+func TestIdempotencyOuterHeaders(t *testing.T) {
+	var reqs int32
+
+	r := clover.New()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-Id", fmt.Sprint(atomic.AddInt32(&reqs, 1)))
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Use(Idempotency(NewIdempotencyMemoryStore(time.Minute)))
+	r.MethodFunc("POST", "/charges", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Charge", "1")
+		w.Write([]byte("charged"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, _ := testIdempotentRequest(t, ts, "abc")
+	assertEqual(t, "1", res.Header.Get("X-Request-Id"))
+
+	res, body := testIdempotentRequest(t, ts, "abc")
+	assertEqual(t, "true", res.Header.Get("Idempotent-Replayed"))
+	assertEqual(t, "2", res.Header.Get("X-Request-Id"))
+	assertEqual(t, "1", res.Header.Get("X-Charge"))
+	assertEqual(t, "charged", body)
+}
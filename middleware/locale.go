@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+var (
+	// LocaleCtxKey is the context.Context key to store the negotiated locale
+	// of a request.
+	LocaleCtxKey = &contextKey{"Locale"}
+)
+
+// Locale is a middleware that negotiates the best locale for a request among
+// the supported ones, based on its Accept-Language header, falling back to
+// defaultLocale when none matches. The locale is stored in the request context,
+// as given in supported, and can be read with GetLocale.
+//
+//	r.Use(middleware.Locale([]string{"en", "fr", "pt-BR"}, "en"))
+func Locale(supported []string, defaultLocale string) func(http.Handler) http.Handler {
+	// The default locale comes first, as the matcher falls back to it.
+	locales := append([]string{defaultLocale}, supported...)
+	tags := make([]language.Tag, len(locales))
+	for i, l := range locales {
+		tags[i] = language.Make(l)
+	}
+	matcher := language.NewMatcher(tags)
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			locale := defaultLocale
+			if accept := r.Header.Get("Accept-Language"); accept != "" {
+				if desired, _, err := language.ParseAcceptLanguage(accept); err == nil && len(desired) > 0 {
+					if _, idx, conf := matcher.Match(desired...); conf != language.No {
+						locale = locales[idx]
+					}
+				}
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), LocaleCtxKey, locale))
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// GetLocale returns the locale negotiated by the Locale middleware, or the
+// empty string if there is none.
+func GetLocale(ctx context.Context) string {
+	locale, _ := ctx.Value(LocaleCtxKey).(string)
+	return locale
+}
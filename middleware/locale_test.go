@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocale(t *testing.T) {
+	h := Locale([]string{"en", "fr", "pt-BR"}, "en")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(GetLocale(r.Context())))
+	}))
+
+	tests := []struct {
+		acceptLanguage string
+		expected       string
+	}{
+		{"fr", "fr"},
+		{"pt-BR,pt;q=0.9", "pt-BR"},
+		{"de-DE,fr;q=0.8,en;q=0.5", "fr"},
+		{"fr-CA", "fr"},
+		{"ja", "en"},
+		{"", "en"},
+		{"!!invalid", "en"},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tc.acceptLanguage != "" {
+			r.Header.Set("Accept-Language", tc.acceptLanguage)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Body.String() != tc.expected {
+			t.Fatalf("%q: expected locale %q, got %q", tc.acceptLanguage, tc.expected, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Maintenance is a middleware that responds 503 Service Unavailable with a
+// Retry-After header while the enabled flag is set, so a site can be flipped
+// into maintenance at runtime without a redeploy. Requests to the allowed
+// paths, ie. health checks, are still served.
+//
+//	var maintenance atomic.Bool
+//	r.Use(middleware.Maintenance(&maintenance, 5*time.Minute, "/healthz"))
+//	...
+//	maintenance.Store(true)
+func Maintenance(enabled *atomic.Bool, retryAfter time.Duration, allowedPaths ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]struct{}, len(allowedPaths))
+	for _, path := range allowedPaths {
+		allowed[path] = struct{}{}
+	}
+	seconds := strconv.FormatInt(int64((retryAfter+time.Second-1)/time.Second), 10)
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if enabled.Load() {
+				if _, ok := allowed[r.URL.Path]; !ok {
+					if retryAfter > 0 {
+						w.Header().Set("Retry-After", seconds)
+					}
+					http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
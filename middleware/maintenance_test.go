@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goclover/clover"
+)
+
+func TestMaintenance(t *testing.T) {
+	var enabled atomic.Bool
+
+	r := clover.NewRouter()
+	r.Use(Maintenance(&enabled, 90*time.Second, "/healthz"))
+	r.HandleFunc("/*", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	if resp, _ := testRequest(t, ts, "GET", "/", nil); resp.StatusCode != 200 {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	enabled.Store(true)
+	resp, _ := testRequest(t, ts, "GET", "/", nil)
+	if resp.StatusCode != 503 {
+		t.Fatalf("expected 503, got %d", resp.StatusCode)
+	}
+	if ra := resp.Header.Get("Retry-After"); ra != "90" {
+		t.Fatalf("unexpected Retry-After %q", ra)
+	}
+	if resp, _ := testRequest(t, ts, "GET", "/healthz", nil); resp.StatusCode != 200 {
+		t.Fatalf("expected the health check to pass, got %d", resp.StatusCode)
+	}
+
+	enabled.Store(false)
+	if resp, _ := testRequest(t, ts, "GET", "/", nil); resp.StatusCode != 200 {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+}
@@ -1,6 +1,13 @@
 package middleware
 
-import "net/http"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
 
 // New will create a new middleware handler from a http.Handler.
 func New(h http.Handler) func(next http.Handler) http.Handler {
@@ -21,3 +28,78 @@ type contextKey struct {
 func (k *contextKey) String() string {
 	return "clover/middleware context value " + k.name
 }
+
+// errBodyTooLarge is returned by peekBody when the body exceeds maxBytes.
+var errBodyTooLarge = errors.New("request body too large")
+
+// peekBody reads up to maxBytes of the request body, without limit when
+// maxBytes isn't positive, and gives the body back whole to the handler. It
+// returns errBodyTooLarge when the body is larger, along with its first
+// maxBytes+1 bytes.
+func peekBody(r *http.Request, maxBytes int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	var src io.Reader = r.Body
+	if maxBytes > 0 {
+		src = io.LimitReader(r.Body, maxBytes+1)
+	}
+	body, err := io.ReadAll(src)
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil {
+		return body, err
+	}
+	if maxBytes > 0 && int64(len(body)) > maxBytes {
+		return body, errBodyTooLarge
+	}
+	return body, nil
+}
+
+// withoutCancel returns a copy of ctx which keeps its values, but isn't
+// canceled along with it and has no deadline, like context.WithoutCancel in
+// Go 1.21.
+func withoutCancel(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// handlerHeaders returns the headers of h the handler set, ie. the ones added
+// or changed since the before snapshot of h taken ahead of the handler, so
+// the headers set by the outer middlewares for the current request aren't
+// recorded along with the response.
+func handlerHeaders(before, h http.Header) http.Header {
+	res := http.Header{}
+	for k, vs := range h {
+		if prev, ok := before[k]; ok && equalValues(prev, vs) {
+			continue
+		}
+		res[k] = append([]string(nil), vs...)
+	}
+	return res
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
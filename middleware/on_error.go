@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// OnError is a middleware that calls fn after the handler whenever the
+// response status is an error, 400 or above, ie. for alerting and error
+// metrics without error detection logic in every handler.
+//
+//	r.Use(middleware.OnError(func(status int, r *http.Request) {
+//		errorsTotal.WithLabelValues(strconv.Itoa(status)).Inc()
+//	}))
+func OnError(fn func(status int, r *http.Request)) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		h := func(w http.ResponseWriter, r *http.Request) {
+			ww := NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			if status := ww.Status(); status >= 400 {
+				fn(status, r)
+			}
+		}
+		return http.HandlerFunc(h)
+	}
+}
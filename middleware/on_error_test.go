@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goclover/clover"
+	"github.com/goclover/clover/render"
+)
+
+func TestOnError(t *testing.T) {
+	var statuses []int
+	var paths []string
+
+	r := clover.NewRouter()
+	r.Use(OnError(func(status int, r *http.Request) {
+		statuses = append(statuses, status)
+		paths = append(paths, r.URL.Path)
+	}))
+	r.MethodFunc("GET", "/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	r.MethodFunc("GET", "/fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	testRequest(t, ts, "GET", "/ok", nil)
+	if len(statuses) != 0 {
+		t.Fatalf("expected no hook call for a 200, got %v", statuses)
+	}
+	testRequest(t, ts, "GET", "/fail", nil)
+	testRequest(t, ts, "GET", "/missing", nil)
+	if len(statuses) != 2 || statuses[0] != 500 || statuses[1] != 404 || paths[0] != "/fail" {
+		t.Fatalf("unexpected hook calls: %v %v", statuses, paths)
+	}
+}
+
+func TestOnErrorEarlyHints(t *testing.T) {
+	var statuses []int
+
+	r := clover.NewRouter()
+	r.Use(OnError(func(status int, r *http.Request) {
+		statuses = append(statuses, status)
+	}))
+	r.MethodFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+		render.EarlyHints("/app.css").WriteTo(w)
+		txt := render.Text("not found")
+		txt.Status = http.StatusNotFound
+		txt.WriteTo(w)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, body := testRequest(t, ts, "GET", "/", nil)
+	if resp.StatusCode != http.StatusNotFound || body != "not found" {
+		t.Fatalf("unexpected response: %d %q", resp.StatusCode, body)
+	}
+	if len(statuses) != 1 || statuses[0] != http.StatusNotFound {
+		t.Fatalf("unexpected hook calls: %v", statuses)
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+var (
+	// TraceHeadersCtxKey is the context.Context key to store the trace
+	// headers of the request, recorded by the RequestID middleware.
+	TraceHeadersCtxKey = &contextKey{"TraceHeaders"}
+
+	// TraceHeaders are the distributed tracing headers of the request which
+	// are propagated to outbound requests along with the request ID.
+	TraceHeaders = []string{"Traceparent", "Tracestate", "Baggage", "B3"}
+)
+
+// traceHeaders returns the TraceHeaders present in h, or nil.
+func traceHeaders(h http.Header) http.Header {
+	var trace http.Header
+	for _, name := range TraceHeaders {
+		if v := h.Values(name); len(v) > 0 {
+			if trace == nil {
+				trace = http.Header{}
+			}
+			trace[http.CanonicalHeaderKey(name)] = v
+		}
+	}
+	return trace
+}
+
+// OutboundContext returns a context for the outbound requests made on behalf
+// of the request in ctx which may outlive it, ie. from a goroutine still
+// running once the response is written. It keeps the request ID and trace
+// headers propagated by OutboundTransport, but isn't canceled along with the
+// request.
+func OutboundContext(ctx context.Context) context.Context {
+	return withoutCancel(ctx)
+}
+
+// OutboundTransport wraps the base http.RoundTripper, http.DefaultTransport
+// if nil, to set the request ID and trace headers of the inbound request on
+// the outbound requests made with its context, closing the correlation loop
+// with downstream services. Headers already set on an outbound request are
+// left untouched.
+//
+//	client := &http.Client{Transport: middleware.OutboundTransport(nil)}
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		req, _ := http.NewRequestWithContext(r.Context(), "GET", "http://users/me", nil)
+//		resp, err := client.Do(req)
+//		...
+//	}
+func OutboundTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &outboundTransport{base: base}
+}
+
+type outboundTransport struct {
+	base http.RoundTripper
+}
+
+func (t *outboundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	reqID := GetReqID(ctx)
+	trace, _ := ctx.Value(TraceHeadersCtxKey).(http.Header)
+	if reqID == "" && trace == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	// a RoundTripper must not modify the request
+	req = req.Clone(ctx)
+	if reqID != "" && req.Header.Get(RequestIDHeader) == "" {
+		req.Header.Set(RequestIDHeader, reqID)
+	}
+	for name, values := range trace {
+		if _, ok := req.Header[name]; !ok {
+			req.Header[name] = values
+		}
+	}
+	return t.base.RoundTrip(req)
+}
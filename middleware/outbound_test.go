@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goclover/clover"
+)
+
+func TestOutboundTransport(t *testing.T) {
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get(RequestIDHeader) + " " + r.Header.Get("Traceparent")))
+	}))
+	defer downstream.Close()
+
+	client := &http.Client{Transport: OutboundTransport(nil)}
+	call := func(ctx context.Context) string {
+		req, _ := http.NewRequestWithContext(ctx, "GET", downstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	r := clover.NewRouter()
+	r.Use(RequestID)
+	r.MethodFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(call(r.Context())))
+	})
+	r.MethodFunc("GET", "/async", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		cancel()
+		w.Write([]byte(call(OutboundContext(ctx))))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.Header.Set("Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assertEqual(t, "req-1 00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", string(body))
+
+	// the outbound context outlives the request
+	req, _ = http.NewRequest("GET", ts.URL+"/async", nil)
+	req.Header.Set(RequestIDHeader, "req-2")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assertEqual(t, "req-2 ", string(body))
+
+	// requests without a request context are sent as is
+	assertEqual(t, " ", call(context.Background()))
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// ReadOnly is a middleware that rejects requests with unsafe methods, such as
+// POST, PUT, PATCH and DELETE, with a 405 response listing the safe methods
+// in its Allow header. It's an easy kill-switch for write traffic, ie. for
+// routes served by a read replica or during an incident.
+func ReadOnly(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !isSafeMethod(r.Method) {
+			w.Header().Set("Allow", "GET, HEAD, OPTIONS, TRACE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goclover/clover"
+)
+
+func TestReadOnly(t *testing.T) {
+	r := clover.NewRouter()
+	r.Use(ReadOnly)
+	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	if resp, body := testRequest(t, ts, "GET", "/", nil); resp.StatusCode != 200 || body != "ok" {
+		t.Fatalf("expected GET to pass, got %d %q", resp.StatusCode, body)
+	}
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+		resp, _ := testRequest(t, ts, method, "/", nil)
+		if resp.StatusCode != 405 {
+			t.Fatalf("%s: expected 405, got %d", method, resp.StatusCode)
+		}
+		if allow := resp.Header.Get("Allow"); allow != "GET, HEAD, OPTIONS, TRACE" {
+			t.Fatalf("%s: unexpected Allow header %q", method, allow)
+		}
+	}
+}
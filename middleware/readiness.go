@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Readiness is a readiness probe middleware serving the `endpoint` path,
+// like Heartbeat, but responding 503 Service Unavailable while the ready
+// flag is cleared, so load balancers stop routing new traffic to the server,
+// ie. while it's draining with Clover.Shutdown.
+//
+//	c := clover.New().WithDrainDelay(10 * time.Second)
+//	c.Use(middleware.Readiness("/readyz", c.Ready()))
+func Readiness(endpoint string, ready *atomic.Bool) func(http.Handler) http.Handler {
+	f := func(h http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method == "GET" || r.Method == "HEAD") && strings.EqualFold(r.URL.Path, endpoint) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("Cache-Control", "no-store")
+				if !ready.Load() {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					w.Write([]byte("not ready"))
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("."))
+				return
+			}
+			h.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+	return f
+}
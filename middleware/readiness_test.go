@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goclover/clover"
+)
+
+func TestReadiness(t *testing.T) {
+	c := clover.New().WithDrainDelay(200 * time.Millisecond)
+	c.Use(Readiness("/readyz", c.Ready()))
+	c.MethodFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	ts := httptest.NewUnstartedServer(c)
+	c.Ser = ts.Config
+	ts.Start()
+	defer ts.Close()
+
+	if resp, body := testRequest(t, ts, "GET", "/readyz", nil); resp.StatusCode != 200 || body != "." {
+		t.Fatalf("expected ready, got %d %q", resp.StatusCode, body)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Shutdown(context.Background())
+	}()
+	for c.Ready().Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the server still serves requests while draining
+	if resp, body := testRequest(t, ts, "GET", "/readyz", nil); resp.StatusCode != 503 || body != "not ready" {
+		t.Fatalf("expected not ready, got %d %q", resp.StatusCode, body)
+	}
+	if _, body := testRequest(t, ts, "GET", "/", nil); body != "ok" {
+		t.Fatalf("expected the request to be served while draining, got %q", body)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := http.Get(ts.URL + "/"); err == nil {
+		t.Fatal("expected the server to be shut down")
+	}
+}
@@ -42,17 +42,19 @@ func TestXForwardForIP(t *testing.T) {
 	r := clover.New()
 	r.Use(RealIP)
 
+	realIP := ""
+	r.MethodFunc("GET","/", func(w http.ResponseWriter, r *http.Request) {
+		realIP = r.RemoteAddr
+		w.Write([]byte("Hello World"))
+	})
+
 	for _, v := range xForwardedForIPs {
 		req, _ := http.NewRequest("GET", "/", nil)
 		req.Header.Add("X-Forwarded-For", v)
 
 		w := httptest.NewRecorder()
 
-		realIP := ""
-		r.MethodFunc("GET","/", func(w http.ResponseWriter, r *http.Request) {
-			realIP = r.RemoteAddr
-			w.Write([]byte("Hello World"))
-		})
+		realIP = ""
 		r.ServeHTTP(w, req)
 
 		if w.Code != 200 {
@@ -20,39 +20,85 @@ import (
 //
 // Alternatively, look at https://github.com/goclover/httplog middleware pkgs.
 func Recoverer(next http.Handler) http.Handler {
-	fn := func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if rvr := recover(); rvr != nil {
-				if rvr == http.ErrAbortHandler {
-					// we don't recover http.ErrAbortHandler so the response
-					// to the client is aborted, this should not be logged
-					panic(rvr)
-				}
+	return RecovererWithOpts(RecovererOpts{})(next)
+}
 
-				logEntry := GetLogEntry(r)
-				if logEntry != nil {
-					logEntry.Panic(rvr, debug.Stack())
-				} else {
-					PrintPrettyStack(rvr)
-				}
+// RecovererOpts represents a set of Recoverer options.
+type RecovererOpts struct {
+	// ShouldLog reports whether a recovered panic value is logged, so
+	// intentional aborts don't clutter the logs. All panics are logged if nil.
+	ShouldLog func(rvr interface{}) bool
 
-				w.WriteHeader(http.StatusInternalServerError)
-			}
-		}()
+	// Status is the status code of the response, 500 Internal Server Error
+	// if zero.
+	Status int
+}
 
-		next.ServeHTTP(w, r)
+// RecovererWithOpts is a middleware that recovers from panics using passed
+// RecovererOpts, see Recoverer.
+func RecovererWithOpts(opts RecovererOpts) func(http.Handler) http.Handler {
+	status := opts.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
 	}
 
-	return http.HandlerFunc(fn)
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if rvr := recover(); rvr != nil {
+					if rvr == http.ErrAbortHandler {
+						// we don't recover http.ErrAbortHandler so the response
+						// to the client is aborted, this should not be logged
+						panic(rvr)
+					}
+
+					if opts.ShouldLog == nil || opts.ShouldLog(rvr) {
+						logEntry := GetLogEntry(r)
+						if logEntry != nil {
+							logEntry.Panic(rvr, debug.Stack())
+						} else {
+							PrintPrettyStack(rvr)
+						}
+					}
+
+					w.WriteHeader(status)
+				}
+			}()
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
 }
 
 // for ability to test the PrintPrettyStack function
 var RecovererErrorWriter io.Writer = os.Stderr
 
+// StackFormatter formats a recovered panic value and the stack trace of the
+// goroutine, as returned by debug.Stack, for PrintPrettyStack to write to
+// the RecovererErrorWriter.
+type StackFormatter interface {
+	FormatStack(rvr interface{}, stack []byte) ([]byte, error)
+}
+
+// StackFormatterFunc is an adapter to use a func as a StackFormatter.
+type StackFormatterFunc func(rvr interface{}, stack []byte) ([]byte, error)
+
+func (f StackFormatterFunc) FormatStack(rvr interface{}, stack []byte) ([]byte, error) {
+	return f(rvr, stack)
+}
+
+// RecovererStackFormatter formats the panics logged by Recoverer, ie. as
+// JSON frames for machine consumption or an error tracker. The default one
+// prints a colored trace for humans, the frame that panicked being
+// highlighted with a `->` prefix. When it fails, the raw stack is printed
+// to stderr.
+var RecovererStackFormatter StackFormatter = prettyStack{}
+
 func PrintPrettyStack(rvr interface{}) {
 	debugStack := debug.Stack()
-	s := prettyStack{}
-	out, err := s.parse(debugStack, rvr)
+	out, err := RecovererStackFormatter.FormatStack(rvr, debugStack)
 	if err == nil {
 		RecovererErrorWriter.Write(out)
 	} else {
@@ -64,6 +110,10 @@ func PrintPrettyStack(rvr interface{}) {
 type prettyStack struct {
 }
 
+func (s prettyStack) FormatStack(rvr interface{}, stack []byte) ([]byte, error) {
+	return s.parse(stack, rvr)
+}
+
 func (s prettyStack) parse(debugStack []byte, rvr interface{}) ([]byte, error) {
 	var err error
 	useColor := true
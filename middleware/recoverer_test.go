@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -41,6 +44,69 @@ func TestRecoverer(t *testing.T) {
 	t.Fatal("First func call line should start with ->.")
 }
 
+func TestRecovererShouldLog(t *testing.T) {
+	oldRecovererErrorWriter := RecovererErrorWriter
+	defer func() { RecovererErrorWriter = oldRecovererErrorWriter }()
+	buf := &bytes.Buffer{}
+	RecovererErrorWriter = buf
+
+	errAbortRender := errors.New("abort render")
+
+	r := clover.New()
+	r.Use(RecovererWithOpts(RecovererOpts{
+		ShouldLog: func(rvr interface{}) bool { return rvr != errAbortRender },
+		Status:    http.StatusServiceUnavailable,
+	}))
+	r.MethodFunc("GET", "/abort", func(w http.ResponseWriter, r *http.Request) {
+		panic(errAbortRender)
+	})
+	r.MethodFunc("GET", "/panic", panicingHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, _ := testRequest(t, ts, "GET", "/abort", nil)
+	assertEqual(t, res.StatusCode, http.StatusServiceUnavailable)
+	if buf.Len() != 0 {
+		t.Fatalf("expected the sentinel panic not to be logged, got %q", buf.String())
+	}
+
+	res, _ = testRequest(t, ts, "GET", "/panic", nil)
+	assertEqual(t, res.StatusCode, http.StatusServiceUnavailable)
+	if !strings.Contains(buf.String(), "panicingHandler") {
+		t.Fatalf("expected the panic to be logged, got %q", buf.String())
+	}
+}
+
+func TestRecovererStackFormatter(t *testing.T) {
+	oldRecovererErrorWriter := RecovererErrorWriter
+	oldRecovererStackFormatter := RecovererStackFormatter
+	defer func() {
+		RecovererErrorWriter = oldRecovererErrorWriter
+		RecovererStackFormatter = oldRecovererStackFormatter
+	}()
+	buf := &bytes.Buffer{}
+	RecovererErrorWriter = buf
+	RecovererStackFormatter = StackFormatterFunc(func(rvr interface{}, stack []byte) ([]byte, error) {
+		return json.Marshal(map[string]interface{}{
+			"panic":  fmt.Sprint(rvr),
+			"frames": strings.Contains(string(stack), "panicingHandler"),
+		})
+	})
+
+	r := clover.New()
+	r.Use(Recoverer)
+	r.MethodFunc("GET", "/", panicingHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, _ := testRequest(t, ts, "GET", "/", nil)
+	assertEqual(t, res.StatusCode, http.StatusInternalServerError)
+	assertEqual(t, strings.TrimSpace(buf.String()), `{"frames":true,"panic":"foo"}`)
+
+}
+
 func TestRecovererAbortHandler(t *testing.T) {
 	defer func() {
 		rcv := recover()
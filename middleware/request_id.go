@@ -60,10 +60,11 @@ func init() {
 }
 
 // RequestID is a middleware that injects a request ID into the context of each
-// request. A request ID is a string of the form "host.example.com/random-0001",
-// where "random" is a base62 random string that uniquely identifies this go
-// process, and where the last number is an atomically incremented request
-// counter.
+// request, along with the TraceHeaders of the request, which OutboundTransport
+// propagates to the outbound requests of the handlers. A request ID is a
+// string of the form "host.example.com/random-0001", where "random" is a
+// base62 random string that uniquely identifies this go process, and where
+// the last number is an atomically incremented request counter.
 func RequestID(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -73,6 +74,9 @@ func RequestID(next http.Handler) http.Handler {
 			requestID = fmt.Sprintf("%s-%06d", prefix, myid)
 		}
 		ctx = context.WithValue(ctx, RequestIDKey, requestID)
+		if trace := traceHeaders(r.Header); trace != nil {
+			ctx = context.WithValue(ctx, TraceHeadersCtxKey, trace)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 	return http.HandlerFunc(fn)
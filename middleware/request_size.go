@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// RequestSize is a middleware that limits the size of every request body to
+// maxBytes by wrapping it in a http.MaxBytesReader. Once a read of the body
+// fails because the limit is exceeded, the response is replaced with a 413
+// Request Entity Too Large. Handlers that don't read the body are unaffected.
+func RequestSize(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			sw := &requestSizeWriter{ResponseWriter: w}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = &requestSizeReader{
+					ReadCloser: http.MaxBytesReader(w, r.Body, maxBytes),
+					w:          sw,
+				}
+			}
+
+			next.ServeHTTP(sw.wrap(), r)
+
+			if sw.exceeded && !sw.wroteHeader {
+				sw.WriteHeader(http.StatusRequestEntityTooLarge)
+			}
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// requestSizeReader flags the response writer once the body limit is hit.
+type requestSizeReader struct {
+	io.ReadCloser
+	w *requestSizeWriter
+}
+
+func (r *requestSizeReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	var mbe *http.MaxBytesError
+	if err != nil && errors.As(err, &mbe) {
+		r.w.exceeded = true
+	}
+	return n, err
+}
+
+// requestSizeWriter replaces the handler's response with a 413 once the
+// request body limit has been exceeded.
+type requestSizeWriter struct {
+	http.ResponseWriter
+	exceeded    bool
+	wroteHeader bool
+	discard     bool
+}
+
+// wrap returns w, implementing the http.Flusher and http.Hijacker
+// interfaces when the ResponseWriter does, ie. for streamed responses.
+func (w *requestSizeWriter) wrap() http.ResponseWriter {
+	_, fl := w.ResponseWriter.(http.Flusher)
+	_, hj := w.ResponseWriter.(http.Hijacker)
+	switch {
+	case fl && hj:
+		return &requestSizeFlushHijackWriter{w}
+	case fl:
+		return &requestSizeFlushWriter{w}
+	case hj:
+		return &requestSizeHijackWriter{w}
+	}
+	return w
+}
+
+func (w *requestSizeWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	if w.exceeded {
+		w.discard = true
+		http.Error(w.ResponseWriter, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *requestSizeWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.discard {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *requestSizeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *requestSizeWriter) flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.discard {
+		w.ResponseWriter.(http.Flusher).Flush()
+	}
+}
+
+// hijack hands the connection over to the handler, which then owns the
+// response.
+func (w *requestSizeWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+type requestSizeFlushWriter struct {
+	*requestSizeWriter
+}
+
+func (f *requestSizeFlushWriter) Flush() { f.flush() }
+
+type requestSizeHijackWriter struct {
+	*requestSizeWriter
+}
+
+func (h *requestSizeHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return h.hijack() }
+
+type requestSizeFlushHijackWriter struct {
+	*requestSizeWriter
+}
+
+func (f *requestSizeFlushHijackWriter) Flush() { f.flush() }
+
+func (f *requestSizeFlushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return f.hijack()
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goclover/clover"
+)
+
+func TestRequestSize(t *testing.T) {
+	r := clover.New()
+	r.Use(RequestSize(10))
+
+	r.MethodFunc("POST", "/read", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	})
+	r.MethodFunc("POST", "/ignore", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ignored"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, body := testRequest(t, ts, "POST", "/read", strings.NewReader("small"))
+	assertEqual(t, http.StatusOK, res.StatusCode)
+	assertEqual(t, "small", body)
+
+	res, _ = testRequest(t, ts, "POST", "/read", strings.NewReader(strings.Repeat("x", 100)))
+	assertEqual(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+
+	res, body = testRequest(t, ts, "POST", "/ignore", strings.NewReader(strings.Repeat("x", 100)))
+	assertEqual(t, http.StatusOK, res.StatusCode)
+	assertEqual(t, "ignored", body)
+}
+
+func TestRequestSizeFlush(t *testing.T) {
+	h := RequestSize(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fl, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("expected the writer to be a http.Flusher")
+		}
+		w.Write([]byte("event: ping\n\n"))
+		fl.Flush()
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !w.Flushed || w.Body.String() != "event: ping\n\n" {
+		t.Fatalf("expected a flushed response, got %v %q", w.Flushed, w.Body.String())
+	}
+
+	// the writer is left as is when it can't flush
+	h = RequestSize(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); ok {
+			t.Fatal("expected the writer not to be a http.Flusher")
+		}
+	}))
+	h.ServeHTTP(struct{ http.ResponseWriter }{httptest.NewRecorder()}, httptest.NewRequest("GET", "/", nil))
+}
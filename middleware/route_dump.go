@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+
+	"github.com/goclover/clover"
+)
+
+// RouteInfo describes a single route served by RouteDump.
+type RouteInfo struct {
+	Method      string   `json:"method"`
+	Pattern     string   `json:"pattern"`
+	Handler     string   `json:"handler"`
+	Middlewares []string `json:"middlewares,omitempty"`
+}
+
+// RouteDump returns a http.Handler that responds with the routing tree of
+// `routes` as JSON, listing the pattern, method, handler and middlewares of
+// every route. It's useful to inspect the live routes of a service, ie.
+//
+//	r.With(middleware.BasicAuth("debug", creds)).
+//	  Mount("/debug/routes", middleware.RouteDump(r))
+//
+// As it exposes the internals of a service, always guard it behind an
+// authentication middleware or only mount it in non-production builds.
+func RouteDump(routes clover.Routes) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		infos := []RouteInfo{}
+		err := clover.Walk(routes, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+			info := RouteInfo{Method: method, Pattern: route, Handler: funcName(handler)}
+			for _, mw := range middlewares {
+				info.Middlewares = append(info.Middlewares, funcName(mw))
+			}
+			infos = append(infos, info)
+			return nil
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sort.Slice(infos, func(i, j int) bool {
+			if infos[i].Pattern != infos[j].Pattern {
+				return infos[i].Pattern < infos[j].Pattern
+			}
+			return infos[i].Method < infos[j].Method
+		})
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(infos)
+	})
+}
+
+// funcName resolves the name of a function value, or the type name of
+// any other value.
+func funcName(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Func {
+		if fn := runtime.FuncForPC(rv.Pointer()); fn != nil {
+			return fn.Name()
+		}
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goclover/clover"
+)
+
+func routeDumpUsers(w http.ResponseWriter, r *http.Request) {}
+
+func TestRouteDump(t *testing.T) {
+	r := clover.New()
+	r.Use(RequestID)
+	r.MethodFunc("GET", "/users", routeDumpUsers)
+	r.Route("/admin", func(r clover.Router) {
+		r.With(NoCache).MethodFunc("POST", "/users/{id}", routeDumpUsers)
+	})
+	r.Mount("/debug/routes", RouteDump(r))
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, body := testRequest(t, ts, "GET", "/debug/routes", nil)
+	assertEqual(t, "application/json; charset=utf-8", res.Header.Get("Content-Type"))
+
+	var infos []RouteInfo
+	if err := json.Unmarshal([]byte(body), &infos); err != nil {
+		t.Fatal(err)
+	}
+
+	var found int
+	for _, info := range infos {
+		switch {
+		case info.Method == "GET" && info.Pattern == "/users":
+			found++
+			assertEqual(t, "github.com/goclover/clover/middleware.routeDumpUsers", info.Handler)
+			assertEqual(t, []string{"github.com/goclover/clover/middleware.RequestID"}, info.Middlewares)
+		case info.Method == "POST" && info.Pattern == "/admin/users/{id}":
+			found++
+			assertEqual(t, "github.com/goclover/clover/middleware.routeDumpUsers", info.Handler)
+			assertEqual(t, []string{
+				"github.com/goclover/clover/middleware.RequestID",
+				"github.com/goclover/clover/middleware.NoCache",
+			}, info.Middlewares)
+		}
+	}
+	assertEqual(t, 2, found)
+}
@@ -23,6 +23,10 @@ import (
 // rSubdomain.Get("/", h2)
 //
 //
+// Host routes match the request Host, normalized as per DNS rules: lowercased,
+// without its port and trailing dot, so "EXAMPLE.com.:443" matches the
+// "example.com" route.
+//
 // Another example, imagine you want to setup multiple CORS handlers, where for
 // your origin servers you allow authorized requests, but for third-party public
 // requests, authorization is disabled.
@@ -52,6 +56,9 @@ type HeaderRouter map[string][]HeaderRoute
 
 func (hr HeaderRouter) Route(header, match string, middlewareHandler func(next http.Handler) http.Handler) HeaderRouter {
 	header = strings.ToLower(header)
+	if header == "host" {
+		match = normalizeHost(match)
+	}
 	k := hr[header]
 	if k == nil {
 		hr[header] = []HeaderRoute{}
@@ -68,6 +75,9 @@ func (hr HeaderRouter) RouteAny(header string, match []string, middlewareHandler
 	}
 	patterns := []Pattern{}
 	for _, m := range match {
+		if header == "host" {
+			m = normalizeHost(m)
+		}
 		patterns = append(patterns, NewPattern(m))
 	}
 	hr[header] = append(hr[header], HeaderRoute{MatchAny: patterns, Middleware: middlewareHandler})
@@ -89,6 +99,10 @@ func (hr HeaderRouter) Handler(next http.Handler) http.Handler {
 		// find first matcloverng header route, and continue
 		for header, matchers := range hr {
 			headerValue := r.Header.Get(header)
+			if header == "host" {
+				// the server moves the Host header to r.Host
+				headerValue = normalizeHost(r.Host)
+			}
 			if headerValue == "" {
 				continue
 			}
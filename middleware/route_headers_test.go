@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteHeadersHost(t *testing.T) {
+	tag := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(name + " "))
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := RouteHeaders().
+		Route("Host", "Example.com", tag("main")).
+		Route("Host", "*.example.com", tag("sub")).
+		RouteDefault(tag("default")).
+		Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+
+	tests := []struct {
+		host string
+		body string
+	}{
+		{"example.com", "main ok"},
+		{"EXAMPLE.COM", "main ok"},
+		{"example.com.", "main ok"},
+		{"example.com:8080", "main ok"},
+		{"EXAMPLE.com.:443", "main ok"},
+		{"API.Example.com.:443", "sub ok"},
+		{"example.org", "default ok"},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Host = tc.host
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Body.String() != tc.body {
+			t.Fatalf("%q: expected %q, got %q", tc.host, tc.body, w.Body.String())
+		}
+	}
+}
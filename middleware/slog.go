@@ -0,0 +1,55 @@
+//go:build go1.21
+
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+var (
+	// SlogCtxKey is the context.Context key to store the request scoped
+	// *slog.Logger.
+	SlogCtxKey = &contextKey{"Slog"}
+)
+
+// ContextLogger is a middleware that injects a request scoped *slog.Logger
+// into the request context, derived from logger with the request ID, method
+// and path fields, so handlers log with them by retrieving it through
+// LoggerFromContext. It's the structured logging companion of RequestLogger,
+// and should go after the RequestID middleware. A nil logger stands for
+// slog.Default(). It's only available with Go 1.21 or newer, which provides
+// log/slog.
+//
+//	r.Use(middleware.RequestID)
+//	r.Use(middleware.ContextLogger(slog.Default()))
+//	...
+//	middleware.LoggerFromContext(ctx).Info("user created", "user", id)
+func ContextLogger(logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			l := logger
+			if l == nil {
+				l = slog.Default()
+			}
+			if reqID := GetReqID(r.Context()); reqID != "" {
+				l = l.With(slog.String("request_id", reqID))
+			}
+			l = l.With(slog.String("method", r.Method), slog.String("path", r.URL.Path))
+
+			r = r.WithContext(context.WithValue(r.Context(), SlogCtxKey, l))
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// LoggerFromContext returns the request scoped *slog.Logger injected by the
+// ContextLogger middleware, or slog.Default() if there is none.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(SlogCtxKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,40 @@
+//go:build go1.21
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+
+	h := RequestID(ContextLogger(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LoggerFromContext(r.Context()).Info("hello", "user", "clover")
+	})))
+
+	r := httptest.NewRequest("GET", "/users", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("unexpected log output %q: %v", buf.String(), err)
+	}
+	for k, v := range map[string]string{"msg": "hello", "request_id": "req-1", "method": "GET", "path": "/users", "user": "clover"} {
+		if entry[k] != v {
+			t.Fatalf("expected %s=%q, got %v", k, v, entry[k])
+		}
+	}
+
+	if LoggerFromContext(context.Background()) != slog.Default() {
+		t.Fatal("expected the default logger without a request scoped one")
+	}
+}
@@ -3,6 +3,8 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/goclover/clover"
 )
@@ -60,3 +62,63 @@ func RedirectSlashes(next http.Handler) http.Handler {
 	}
 	return http.HandlerFunc(fn)
 }
+
+// StripPrefix is a middleware that strips the prefix from the request path
+// and continues routing through the mux with the remaining path, keeping the
+// route context in sync so URL params and route patterns still resolve.
+// Requests whose path doesn't start with the prefix get a 404, like
+// http.StripPrefix.
+//
+// It's handy to serve a router under a sub-path behind a proxy that doesn't
+// rewrite the request path.
+func StripPrefix(prefix string) func(http.Handler) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			rctx := clover.RouteContext(r.Context())
+			path := r.URL.Path
+			if rctx != nil && rctx.RoutePath != "" {
+				path = rctx.RoutePath
+			}
+
+			newPath, ok := stripPrefix(path, prefix)
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			if rctx != nil {
+				rctx.RoutePath = newPath
+			}
+
+			if p, ok := stripPrefix(r.URL.Path, prefix); ok {
+				r2 := new(http.Request)
+				*r2 = *r
+				r2.URL = new(url.URL)
+				*r2.URL = *r.URL
+				r2.URL.Path = p
+				if rp, ok := stripPrefix(r.URL.RawPath, prefix); ok && r.URL.RawPath != "" {
+					r2.URL.RawPath = rp
+				}
+				r = r2
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// stripPrefix trims prefix from path on a path segment boundary, returning
+// "/" when nothing is left.
+func stripPrefix(path, prefix string) (string, bool) {
+	if !strings.HasPrefix(path, prefix) {
+		return "", false
+	}
+	rest := path[len(prefix):]
+	if rest == "" {
+		return "/", true
+	}
+	if rest[0] != '/' {
+		return "", false
+	}
+	return rest, true
+}
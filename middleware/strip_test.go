@@ -237,3 +237,34 @@ func TestStripSlashesWithNilContext(t *testing.T) {
 		t.Fatalf(resp)
 	}
 }
+
+func TestStripPrefix(t *testing.T) {
+	r := clover.New()
+	r.Use(StripPrefix("/app/"))
+
+	r.MethodFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("root " + r.URL.Path))
+	})
+	r.Route("/accounts/{accountID}", func(r clover.Router) {
+		r.MethodFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+			rctx := clover.RouteContext(r.Context())
+			w.Write([]byte(clover.URLParam(r, "accountID") + " " + rctx.RoutePattern()))
+		})
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	if _, resp := testRequest(t, ts, "GET", "/app", nil); resp != "root /" {
+		t.Fatalf(resp)
+	}
+	if _, resp := testRequest(t, ts, "GET", "/app/accounts/admin", nil); resp != "admin /accounts/{accountID}" {
+		t.Fatalf(resp)
+	}
+	if resp, _ := testRequest(t, ts, "GET", "/application/accounts/admin", nil); resp.StatusCode != 404 {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+	if resp, _ := testRequest(t, ts, "GET", "/accounts/admin", nil); resp.StatusCode != 404 {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
@@ -1,13 +1,25 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
+	"net"
 	"net/http"
+	"sync"
 	"time"
+
+	"github.com/goclover/clover/render"
 )
 
 // Timeout is a middleware that cancels ctx after a given timeout and return
-// a 504 Gateway Timeout error to the client.
+// a 504 Gateway Timeout error to the client. When the request context is
+// canceled instead, ie. as the server shuts down, it returns a 503 Service
+// Unavailable error, so clients can tell whether retrying is worthwhile.
+//
+// Once the context is done, writes of the handler fail with
+// http.ErrHandlerTimeout. A response whose headers were already sent, such as
+// a SSE or NDJSON stream, keeps the data written so far and ends cleanly when
+// the handler returns, without a 504 written over it.
 //
 // It's required that you select the ctx.Done() channel to check for the signal
 // if the context has reached its deadline and return, otherwise the timeout
@@ -31,19 +43,188 @@ import (
 //  })
 //
 func Timeout(timeout time.Duration) func(next http.Handler) http.Handler {
+	return TimeoutWithOpts(TimeoutOpts{Timeout: timeout})
+}
+
+// TimeoutOpts represents a set of timeout options.
+type TimeoutOpts struct {
+	Timeout time.Duration
+
+	// DeadlineStatus is the status returned once the timeout is reached,
+	// 504 Gateway Timeout if zero.
+	DeadlineStatus int
+
+	// CanceledStatus is the status returned when the request context is
+	// canceled before the timeout, 503 Service Unavailable if zero.
+	CanceledStatus int
+
+	// Render optionally builds the response written instead of the bare
+	// status, ie. a JSON error for API clients. err is the error of the
+	// request context, context.DeadlineExceeded or context.Canceled. A render
+	// writing 200 OK is sent with the DeadlineStatus or CanceledStatus.
+	//
+	//	Render: func(r *http.Request, err error) render.Render {
+	//		return render.JSON(map[string]string{"error": "request timed out"})
+	//	},
+	Render func(r *http.Request, err error) render.Render
+}
+
+// TimeoutWithOpts is a middleware that cancels ctx after a given timeout using
+// passed TimeoutOpts, see Timeout.
+func TimeoutWithOpts(opts TimeoutOpts) func(next http.Handler) http.Handler {
+	if opts.DeadlineStatus == 0 {
+		opts.DeadlineStatus = http.StatusGatewayTimeout
+	}
+	if opts.CanceledStatus == 0 {
+		opts.CanceledStatus = http.StatusServiceUnavailable
+	}
+
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			ctx, cancel := context.WithTimeout(r.Context(), opts.Timeout)
+			tw := &timeoutWriter{ResponseWriter: w, ctx: ctx}
 			defer func() {
+				// check before cancel(), which sets context.Canceled
+				err := ctx.Err()
 				cancel()
-				if ctx.Err() == context.DeadlineExceeded {
-					w.WriteHeader(http.StatusGatewayTimeout)
+				if err == nil || !tw.timeout() {
+					return
+				}
+
+				status := opts.DeadlineStatus
+				if err == context.Canceled {
+					status = opts.CanceledStatus
+				}
+				if opts.Render == nil {
+					w.WriteHeader(status)
+					return
 				}
+				_ = opts.Render(r, err).WriteTo(&statusWriter{ResponseWriter: w, status: status})
 			}()
 
 			r = r.WithContext(ctx)
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(tw.wrap(), r)
 		}
 		return http.HandlerFunc(fn)
 	}
 }
+
+// timeoutWriter discards the writes of the handler once the request context
+// is done. A response already committed, ie. a stream, is left as is for
+// the middleware.
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx context.Context
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+// timeout marks the response as timed out, and reports whether it's still
+// up to the middleware to write it.
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	return !tw.wroteHeader
+}
+
+// discard reports whether a write must be dropped, committing the response
+// otherwise.
+func (tw *timeoutWriter) discard() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.ctx.Err() != nil {
+		return true
+	}
+	tw.wroteHeader = true
+	return false
+}
+
+// wrap returns tw, implementing the http.Flusher and http.Hijacker
+// interfaces when the ResponseWriter does, ie. for websocket upgrades.
+func (tw *timeoutWriter) wrap() http.ResponseWriter {
+	_, fl := tw.ResponseWriter.(http.Flusher)
+	_, hj := tw.ResponseWriter.(http.Hijacker)
+	switch {
+	case fl && hj:
+		return &timeoutFlushHijackWriter{tw}
+	case fl:
+		return &timeoutFlushWriter{tw}
+	case hj:
+		return &timeoutHijackWriter{tw}
+	}
+	return tw
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		tw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if !tw.discard() {
+		tw.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	if tw.discard() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.ResponseWriter.Write(p)
+}
+
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+func (tw *timeoutWriter) flush() {
+	if !tw.discard() {
+		tw.ResponseWriter.(http.Flusher).Flush()
+	}
+}
+
+// hijack hands the connection over to the handler, which then owns the
+// response, unless the request already timed out.
+func (tw *timeoutWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if tw.discard() {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	return tw.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+type timeoutFlushWriter struct {
+	*timeoutWriter
+}
+
+func (f *timeoutFlushWriter) Flush() { f.flush() }
+
+type timeoutHijackWriter struct {
+	*timeoutWriter
+}
+
+func (h *timeoutHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return h.hijack() }
+
+type timeoutFlushHijackWriter struct {
+	*timeoutWriter
+}
+
+func (f *timeoutFlushHijackWriter) Flush() { f.flush() }
+
+func (f *timeoutFlushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return f.hijack()
+}
+
+// statusWriter replaces the 200 OK status written to the ResponseWriter.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if code == http.StatusOK {
+		code = sw.status
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goclover/clover"
+	"github.com/goclover/clover/render"
+)
+
+func TestTimeout(t *testing.T) {
+	wait := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	// the timeout is reached
+	w := httptest.NewRecorder()
+	Timeout(10*time.Millisecond)(wait).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", w.Code)
+	}
+
+	// the request is canceled before the timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	w = httptest.NewRecorder()
+	Timeout(time.Minute)(wait).ServeHTTP(w, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+
+	// the request completes in time
+	w = httptest.NewRecorder()
+	Timeout(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	})).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "done" {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	// custom statuses
+	w = httptest.NewRecorder()
+	TimeoutWithOpts(TimeoutOpts{Timeout: 10 * time.Millisecond, DeadlineStatus: http.StatusRequestTimeout})(wait).
+		ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusRequestTimeout {
+		t.Fatalf("expected 408, got %d", w.Code)
+	}
+}
+
+func TestTimeoutRender(t *testing.T) {
+	late := make(chan error, 1)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		_, err := w.Write([]byte("late"))
+		late <- err
+	})
+	mw := TimeoutWithOpts(TimeoutOpts{
+		Timeout: 10 * time.Millisecond,
+		Render: func(r *http.Request, err error) render.Render {
+			return render.JSON(map[string]string{"error": err.Error()})
+		},
+	})
+
+	w := httptest.NewRecorder()
+	mw(h).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if body := w.Body.String(); body != `{"error":"context deadline exceeded"}` {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if err := <-late; err != http.ErrHandlerTimeout {
+		t.Fatalf("expected the late write to be discarded, got %v", err)
+	}
+
+	// the response is left alone when the handler completes in time
+	w = httptest.NewRecorder()
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	})).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "done" {
+		t.Fatalf("expected 201 done, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestTimeoutStream(t *testing.T) {
+	defer func(l *log.Logger) { clover.ErrorLog = l }(clover.ErrorLog)
+	clover.ErrorLog = log.New(io.Discard, "", 0)
+
+	r := clover.New()
+	r.Use(Timeout(90 * time.Millisecond))
+	r.Method("GET", "/stream", func(ctx context.Context, r *http.Request) render.Render {
+		ch := make(chan interface{})
+		go func() {
+			defer close(ch)
+			for n := 1; ; n++ {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(20 * time.Millisecond):
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- map[string]int{"n": n}:
+				}
+			}
+		}()
+		return render.JSONStream(ch)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, body := testRequest(t, ts, "GET", "/stream", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the committed 200 to be kept, got %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(body, `[{"n":1},{"n":2}`) {
+		t.Fatalf("expected the elements written before the timeout, got %q", body)
+	}
+	if strings.Contains(body, "]") || strings.Contains(body, "Error") || strings.Contains(body, "Timeout") {
+		t.Fatalf("expected the stream to be cut short, got %q", body)
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (h hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestTimeoutWriterInterfaces(t *testing.T) {
+	tests := []struct {
+		name  string
+		w     http.ResponseWriter
+		flush bool
+		hj    bool
+	}{
+		{"plain", struct{ http.ResponseWriter }{httptest.NewRecorder()}, false, false},
+		{"flusher", httptest.NewRecorder(), true, false},
+		{"hijacker", struct {
+			http.ResponseWriter
+			http.Hijacker
+		}{httptest.NewRecorder(), hijackRecorder{}}, false, true},
+		{"both", hijackRecorder{httptest.NewRecorder()}, true, true},
+	}
+
+	for _, tc := range tests {
+		var flush, hj bool
+		h := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, flush = w.(http.Flusher)
+			_, hj = w.(http.Hijacker)
+		}))
+		h.ServeHTTP(tc.w, httptest.NewRequest("GET", "/", nil))
+		if flush != tc.flush || hj != tc.hj {
+			t.Fatalf("%s: expected flusher=%v hijacker=%v, got %v %v", tc.name, tc.flush, tc.hj, flush, hj)
+		}
+	}
+}
@@ -45,32 +45,73 @@ var (
 // }
 //
 func URLFormat(next http.Handler) http.Handler {
-	fn := func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+	return URLFormatWithOpts(URLFormatOpts{})(next)
+}
 
-		var format string
-		path := r.URL.Path
+// URLFormatOpts represents a set of URLFormat options.
+type URLFormatOpts struct {
+	// MatchedOnly only trims the extension when the trimmed path matches a
+	// registered route, so routes with a literal dotted segment, ie.
+	// `/config.json`, keep being served as is. The format is left empty
+	// when the path is preserved.
+	MatchedOnly bool
+}
 
-		rctx := clover.RouteContext(r.Context())
-		if rctx != nil && rctx.RoutePath != "" {
-			path = rctx.RoutePath
-		}
+// URLFormatWithOpts is a URLFormat middleware using the passed URLFormatOpts.
+func URLFormatWithOpts(opts URLFormatOpts) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
 
-		if strings.Index(path, ".") > 0 {
-			base := strings.LastIndex(path, "/")
-			idx := strings.LastIndex(path[base:], ".")
+			var format string
+			path := r.URL.Path
 
-			if idx > 0 {
-				idx += base
-				format = path[idx+1:]
+			rctx := clover.RouteContext(r.Context())
+			if rctx != nil && rctx.RoutePath != "" {
+				path = rctx.RoutePath
+			}
 
-				rctx.RoutePath = path[:idx]
+			if strings.Index(path, ".") > 0 {
+				base := strings.LastIndex(path, "/")
+				idx := strings.LastIndex(path[base:], ".")
+
+				if idx > 0 {
+					idx += base
+					if !opts.MatchedOnly || urlFormatMatches(r, rctx, len(path)-idx) {
+						format = path[idx+1:]
+						rctx.RoutePath = path[:idx]
+					}
+				}
 			}
-		}
 
-		r = r.WithContext(context.WithValue(ctx, URLFormatCtxKey, format))
+			r = r.WithContext(context.WithValue(ctx, URLFormatCtxKey, format))
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
 
-		next.ServeHTTP(w, r)
+// urlFormatMatches reports whether the request path, without its trailing
+// extension of extLen bytes, matches a route of the router. The full path
+// is looked up as the router in the context is always the root one.
+func urlFormatMatches(r *http.Request, rctx *clover.Context, extLen int) bool {
+	if rctx == nil || rctx.Routes == nil {
+		return false
+	}
+	path := r.URL.Path
+	if r.URL.RawPath != "" {
+		path = r.URL.RawPath
 	}
-	return http.HandlerFunc(fn)
+	if extLen > len(path) {
+		return false
+	}
+	method := rctx.RouteMethod
+	if method == "" {
+		method = r.Method
+	}
+
+	// Temporary routing context to look-ahead before routing the request
+	tctx := clover.NewRouteContext()
+	return rctx.Routes.Match(tctx, method, path[:len(path)-extLen])
 }
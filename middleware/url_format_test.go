@@ -67,3 +67,33 @@ func TestURLFormatInSubRouter(t *testing.T) {
 		t.Fatalf(resp)
 	}
 }
+
+func TestURLFormatMatchedOnly(t *testing.T) {
+	r := clover.New()
+
+	r.Use(URLFormatWithOpts(URLFormatOpts{MatchedOnly: true}))
+
+	r.MethodFunc("GET", "/config.json", func(w http.ResponseWriter, r *http.Request) {
+		format, _ := r.Context().Value(URLFormatCtxKey).(string)
+		w.Write([]byte("config:" + format))
+	})
+	r.Route("/articles", func(r clover.Router) {
+		r.MethodFunc("GET", "/{articleID}", func(w http.ResponseWriter, r *http.Request) {
+			format, _ := r.Context().Value(URLFormatCtxKey).(string)
+			w.Write([]byte(clover.URLParam(r, "articleID") + ":" + format))
+		})
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	if _, resp := testRequest(t, ts, "GET", "/config.json", nil); resp != "config:" {
+		t.Fatalf(resp)
+	}
+	if _, resp := testRequest(t, ts, "GET", "/articles/1.json", nil); resp != "1:json" {
+		t.Fatalf(resp)
+	}
+	if _, resp := testRequest(t, ts, "GET", "/articles/1", nil); resp != "1:" {
+		t.Fatalf(resp)
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// JSONSchema validates a decoded JSON document. It's meant to adapt the
+// compiled schema of a JSON Schema library, returning FieldErrors to report
+// the offending fields.
+type JSONSchema interface {
+	Validate(doc interface{}) error
+}
+
+// JSONSchemaFunc is an adapter to use a func as a JSONSchema.
+type JSONSchemaFunc func(doc interface{}) error
+
+// Validate calls f(doc).
+func (f JSONSchemaFunc) Validate(doc interface{}) error {
+	return f(doc)
+}
+
+// FieldError is the validation failure of a single field of a document.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldErrors is a JSONSchema validation error listing the invalid fields.
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateJSON is a middleware that validates the JSON request body against
+// the schema, responding 400 Bad Request to a malformed body and 422
+// Unprocessable Entity to an invalid one, with a JSON body such as
+// {"error": "...", "fields": [{"field": "name", "message": "is required"}]}.
+// The body is buffered and restored, so the handler can still read it.
+// Bodies larger than 1MB are rejected with a 413 Request Entity Too Large,
+// see ValidateJSONWithOpts to change the limit.
+func ValidateJSON(schema JSONSchema) func(next http.Handler) http.Handler {
+	return ValidateJSONWithOpts(ValidateJSONOpts{Schema: schema})
+}
+
+// ValidateJSONOpts represents a set of ValidateJSON options.
+type ValidateJSONOpts struct {
+	// Schema validates the decoded request body.
+	Schema JSONSchema
+
+	// MaxBytes caps the size of the buffered request body, 1MB if zero.
+	MaxBytes int64
+}
+
+// ValidateJSONWithOpts is a middleware that validates the JSON request body
+// using passed ValidateJSONOpts.
+func ValidateJSONWithOpts(opts ValidateJSONOpts) func(next http.Handler) http.Handler {
+	if opts.Schema == nil {
+		panic("clover/middleware: ValidateJSON expects a schema")
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 1 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil {
+				validationError(w, http.StatusBadRequest, "request body is empty", nil)
+				return
+			}
+			body, err := peekBody(r, opts.MaxBytes)
+			if err == errBodyTooLarge {
+				validationError(w, http.StatusRequestEntityTooLarge, err.Error(), nil)
+				return
+			}
+			if err != nil {
+				validationError(w, http.StatusBadRequest, err.Error(), nil)
+				return
+			}
+
+			var doc interface{}
+			dec := json.NewDecoder(bytes.NewReader(body))
+			dec.UseNumber()
+			if err := dec.Decode(&doc); err != nil {
+				validationError(w, http.StatusBadRequest, "invalid JSON: "+err.Error(), nil)
+				return
+			}
+
+			if err := opts.Schema.Validate(doc); err != nil {
+				fields, _ := err.(FieldErrors)
+				validationError(w, http.StatusUnprocessableEntity, err.Error(), fields)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func validationError(w http.ResponseWriter, status int, msg string, fields FieldErrors) {
+	b, _ := json.Marshal(struct {
+		Error  string      `json:"error"`
+		Fields FieldErrors `json:"fields,omitempty"`
+	}{msg, fields})
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(b)
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateJSON(t *testing.T) {
+	schema := JSONSchemaFunc(func(doc interface{}) error {
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return FieldErrors{{Field: "", Message: "must be an object"}}
+		}
+		var errs FieldErrors
+		for _, field := range []string{"name", "email"} {
+			if _, ok := obj[field]; !ok {
+				errs = append(errs, FieldError{Field: field, Message: "is required"})
+			}
+		}
+		if errs != nil {
+			return errs
+		}
+		return nil
+	})
+
+	h := ValidateJSON(schema)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	tests := []struct {
+		body   string
+		status int
+		resp   string
+	}{
+		{`{"name":"clover","email":"c@example.com"}`, 200, `{"name":"clover","email":"c@example.com"}`},
+		{`{"name":"clover"}`, 422, `{"error":"email: is required","fields":[{"field":"email","message":"is required"}]}`},
+		{`{"name":`, 400, `{"error":"invalid JSON: unexpected EOF"}`},
+	}
+
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(tc.body)))
+		if w.Code != tc.status || w.Body.String() != tc.resp {
+			t.Fatalf("%q: expected %d %s, got %d %s", tc.body, tc.status, tc.resp, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestValidateJSONMaxBytes(t *testing.T) {
+	schema := JSONSchemaFunc(func(doc interface{}) error { return nil })
+	h := ValidateJSONWithOpts(ValidateJSONOpts{Schema: schema, MaxBytes: 16})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"clover"}`)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"ok"}`)))
+	if w.Code != http.StatusOK || w.Body.String() != `{"name":"ok"}` {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
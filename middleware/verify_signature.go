@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// HashAlgo is the hash function of a HMAC signature.
+type HashAlgo int
+
+const (
+	HashSHA1 HashAlgo = iota + 1
+	HashSHA256
+	HashSHA512
+)
+
+func (a HashAlgo) new() func() hash.Hash {
+	switch a {
+	case HashSHA1:
+		return sha1.New
+	case HashSHA256:
+		return sha256.New
+	case HashSHA512:
+		return sha512.New
+	}
+	panic("clover/middleware: unknown HashAlgo")
+}
+
+// String returns the name of the algorithm, as used in the prefix of the
+// signature header, ie. "sha256".
+func (a HashAlgo) String() string {
+	switch a {
+	case HashSHA1:
+		return "sha1"
+	case HashSHA256:
+		return "sha256"
+	case HashSHA512:
+		return "sha512"
+	}
+	return "unknown"
+}
+
+// VerifySignature is a middleware verifying the HMAC signature of webhook
+// requests, such as GitHub's X-Hub-Signature-256, rejecting the ones without
+// a valid signature with a 401 Unauthorized. The signature is read from the
+// header as hex, either bare or prefixed by the algorithm name, ie.
+// "sha256=<hex>". The body is buffered and restored, so the handler can still
+// read it; mount RequestSize before to cap its size.
+//
+//	r.Use(middleware.VerifySignature(secret, "X-Hub-Signature-256", middleware.HashSHA256))
+func VerifySignature(secret []byte, header string, algo HashAlgo) func(next http.Handler) http.Handler {
+	newHash := algo.new()
+	prefix := algo.String() + "="
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			sig, err := hex.DecodeString(strings.TrimPrefix(r.Header.Get(header), prefix))
+			if err != nil || len(sig) == 0 {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			body, err := peekBody(r, 0)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+
+			mac := hmac.New(newHash, secret)
+			mac.Write(body)
+			if !hmac.Equal(sig, mac.Sum(nil)) {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
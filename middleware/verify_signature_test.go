@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("s3cret")
+	body := `{"action":"opened"}`
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	h := VerifySignature(secret, "X-Hub-Signature-256", HashSHA256)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Write(b)
+	}))
+
+	tests := []struct {
+		signature string
+		status    int
+	}{
+		{"sha256=" + sig, 200},
+		{sig, 200},
+		{"sha256=" + strings.Repeat("0", len(sig)), 401},
+		{"sha1=" + sig, 401},
+		{"sha256=not-hex", 401},
+		{"", 401},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+		if tc.signature != "" {
+			r.Header.Set("X-Hub-Signature-256", tc.signature)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Fatalf("%q: expected %d, got %d", tc.signature, tc.status, w.Code)
+		}
+		if tc.status == 200 && w.Body.String() != body {
+			t.Fatalf("%q: expected the body to be restored, got %q", tc.signature, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/goclover/clover"
+)
+
+// WrapRequest is a middleware that builds the clover.Request wrapper once per
+// request and stores it in the request context, so handlers can fetch it with
+// clover.ReqOf(ctx) instead of calling clover.NewRequest(r) themselves.
+//
+// Middlewares that replace the request context should be placed before
+// WrapRequest, as the wrapper keeps a reference to the request it was
+// built from.
+func WrapRequest(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, clover.WithRequest(r))
+	}
+	return http.HandlerFunc(fn)
+}
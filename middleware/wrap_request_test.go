@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goclover/clover"
+)
+
+func TestWrapRequest(t *testing.T) {
+	r := clover.New()
+	r.Use(WrapRequest)
+
+	var first clover.Request
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			first = clover.ReqOf(r.Context())
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.MethodFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+		req := clover.ReqOf(r.Context())
+		if req == nil {
+			t.Fatal("expected a request wrapper in the context")
+		}
+		if req != first {
+			t.Fatal("expected the same request wrapper instance")
+		}
+		if clover.ReqOf(req.HTTPRequest().Context()) != req {
+			t.Fatal("expected the wrapped request to carry the wrapper")
+		}
+		w.Write([]byte(req.QueryDefault("name", "none")))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	_, body := testRequest(t, ts, "GET", "/?name=clover", nil)
+	assertEqual(t, "clover", body)
+}
@@ -74,6 +74,14 @@ type basicWriter struct {
 }
 
 func (b *basicWriter) WriteHeader(code int) {
+	// Informational responses, ie. 103 Early Hints, are sent ahead of the
+	// final one, except for 101 Switching Protocols which ends the exchange.
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		if !b.wroteHeader {
+			b.ResponseWriter.WriteHeader(code)
+		}
+		return
+	}
 	if !b.wroteHeader {
 		b.code = code
 		b.wroteHeader = true
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 var _ Router = &Mux{}
@@ -26,6 +29,9 @@ type Mux struct {
 	// The radix trie router
 	tree *node
 
+	// Map index of the tree, used while all routes are static
+	static *staticIndex
+
 	// Custom method not allowed handler
 	methodNotAllowedHandler http.HandlerFunc
 
@@ -39,18 +45,48 @@ type Mux struct {
 	// Custom route not found handler
 	notFoundHandler http.HandlerFunc
 
+	// Handler for unmatched routing paths, invoked before the not found handler
+	fallbackHandler http.Handler
+
 	// The middleware stack
 	middlewares []func(http.Handler) http.Handler
 
 	// Controls the behaviour of middleware chain generation when a mux
 	// is registered as an inline group inside another mux.
 	inline bool
+
+	// Per-request timeout applied to the request context, if non-zero
+	requestTimeout time.Duration
+
+	// Callbacks invoked whenever a route is registered
+	routeHooks []func(method, pattern string, h http.Handler)
+
+	// Predicate guarded handlers by routing pattern, see MatchFunc
+	matchRoutes map[string][]matchRoute
+
+	// Accept `:param` segments in routing patterns, see ColonSyntax
+	colonSyntax bool
+
+	// Leading methods of the Allow header on 405, see AllowOrder
+	allowOrder []string
+
+	// Route on the decoded request path, see UseRawPath
+	decodedPath bool
+
+	// Named middleware stacks, see UseStack
+	stacks map[string]Middlewares
+}
+
+// matchRoute is a handler guarded by a predicate on the request.
+type matchRoute struct {
+	match   func(r *http.Request) bool
+	handler http.Handler
 }
 
 // newMux returns a newly initialized Mux object that implements the Router
 // interface.
 func newMux() *Mux {
-	mux := &Mux{tree: &node{}, pool: &sync.Pool{}}
+	mux := &Mux{tree: &node{}, static: newStaticIndex(), pool: &sync.Pool{}}
 	mux.pool.New = func() interface{} {
 		return NewRouteContext()
 	}
@@ -83,8 +119,15 @@ func (mx *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	rctx.Routes = mx
 	rctx.parentCtx = r.Context()
 
+	ctx := r.Context()
+	if mx.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, mx.requestTimeout)
+		defer cancel()
+	}
+
 	// NOTE: r.WithContext() causes 2 allocations and context.WithValue() causes 1 allocation
-	r = r.WithContext(context.WithValue(r.Context(), RouteCtxKey, rctx))
+	r = r.WithContext(context.WithValue(ctx, RouteCtxKey, rctx))
 
 	// Serve the request and once its done, put the request context back in the sync pool
 	mx.handler.ServeHTTP(w, r)
@@ -97,6 +140,12 @@ func (mx *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // route to a specific handler, which provides opportunity to respond early,
 // change the course of the request execution, or set request-scoped values for
 // the next http.Handler.
+//
+// Middlewares run in registration order on the way in, and in reverse order
+// on the way out, the first registered being the outermost: with
+// Use(a, b) and a route With(c), a request goes through a, b, c, the
+// handler, then back through c, b and a. The middlewares of a parent router
+// wrap the ones of its subrouters and inline groups the same way.
 func (mx *Mux) Use(middlewares ...func(http.Handler) http.Handler) {
 	if mx.handler != nil {
 		panic("clover: all middlewares must be defined before routes on a mux")
@@ -104,6 +153,51 @@ func (mx *Mux) Use(middlewares ...func(http.Handler) http.Handler) {
 	mx.middlewares = append(mx.middlewares, middlewares...)
 }
 
+// RequestTimeout sets a timeout on the context of every request served by
+// the Mux, so handlers observe ctx.Done() once it expires, the same way they
+// do when the client goes away. Unlike middleware.Timeout, no response is
+// written on expiry. It only applies to the root router.
+func (mx *Mux) RequestTimeout(timeout time.Duration) {
+	mx.requestTimeout = timeout
+}
+
+// ColonSyntax enables the `:param` syntax for the routing patterns registered
+// on the Mux from this point forward, easing the migration from routers using
+// it. A segment starting with ':' is translated to the `{param}` form at
+// registration, so "/users/:id" is the same route as "/users/{id}" and its
+// param is read with URLParam(r, "id"). The brace syntax, including regexp
+// params, keeps working alongside it. Subrouters created with Route and
+// PathPrefix inherit the mode.
+func (mx *Mux) ColonSyntax(enabled bool) {
+	mx.root().colonSyntax = enabled
+}
+
+// UseRawPath sets whether requests are routed on their raw path, as sent by
+// the client, which is the default, or on the decoded path. Unlike
+// http.ServeMux, the path isn't cleaned in either mode.
+//
+// On the raw path an encoded slash doesn't separate segments, so
+// "/files/a%2Fb" matches "/files/{id}", and the params keep their encoding,
+// ie. URLParam(r, "id") is "a%2Fb", to be decoded with url.PathUnescape. The
+// raw path is only used when it isn't the default encoding of the decoded
+// path, see url.URL.RawPath, so "/files/a%20b" still yields "a b". On the
+// decoded path an encoded slash is routed as a separator, "/files/a/b". The
+// option only applies to the root router.
+func (mx *Mux) UseRawPath(use bool) {
+	mx.root().decodedPath = !use
+}
+
+// OnRoute registers a callback invoked whenever a route is registered on the
+// Mux, or on any of its inline groups, from this point forward. The method is
+// "*" for routes matching any http method. Routes of a subrouter are reported
+// with their full pattern when it's mounted, and so are the ones added to a
+// Route or PathPrefix subrouter afterwards, which makes OnRoute a convenient
+// extension point for instrumentation, ie. registering per-route metrics.
+func (mx *Mux) OnRoute(fn func(method, pattern string, h http.Handler)) {
+	m := mx.root()
+	m.routeHooks = append(m.routeHooks, fn)
+}
+
 // Handle adds the route `pattern` that matches any http method to
 // execute the `handler` http.Handler.
 func (mx *Mux) Handle(pattern string, handler HandlerFunc) {
@@ -148,12 +242,174 @@ func (mx *Mux) MethodStd(method, pattern string, handler http.Handler) {
 	}
 }
 
+// RouteDef is the definition of a route in a routing table registered with
+// RegisterRoutes.
+type RouteDef struct {
+	// Method is the http method of the route, or a comma separated list of
+	// methods. The route matches any method if empty or "*".
+	Method      string
+	Pattern     string
+	Handler     http.Handler
+	Middlewares []func(http.Handler) http.Handler
+}
+
+// RegisterRoutes registers the routes of a routing table, applying the
+// middlewares of each route inline, as With does. It's handy for config
+// driven apps and generated API servers.
+func (mx *Mux) RegisterRoutes(routes []RouteDef) {
+	for _, rd := range routes {
+		var r Router = mx
+		if len(rd.Middlewares) > 0 {
+			r = mx.With(rd.Middlewares...)
+		}
+		if rd.Method == "" || rd.Method == "*" {
+			r.HandleStd(rd.Pattern, rd.Handler)
+		} else {
+			r.MethodStd(rd.Method, rd.Pattern, rd.Handler)
+		}
+	}
+}
+
+// Register adds the route `pattern` that matches the `method` http method,
+// a comma separated list of methods, or any method if empty or "*", to
+// execute the `handler` http.Handler, and returns a handle on the route to
+// configure it fluently right where it's defined.
+//
+//	r.Register("GET", "/users/{id}", getUser).
+//		Doc(clover.RouteDoc{Summary: "Get a user"})
+func (mx *Mux) Register(method, pattern string, handler http.Handler) *RouteHandle {
+	if method == "" || method == "*" {
+		mx.HandleStd(pattern, handler)
+		return &RouteHandle{mx: mx, methods: []string{"*"}, pattern: pattern}
+	}
+	mx.MethodStd(method, pattern, handler)
+	return &RouteHandle{mx: mx, methods: strings.Split(method, ","), pattern: pattern}
+}
+
+// RouteHandle is a route registered with Register. Its methods apply to the
+// route's methods and pattern, as their Mux counterparts do, and return the
+// handle to be chained.
+type RouteHandle struct {
+	mx      *Mux
+	methods []string
+	pattern string
+}
+
+// Doc attaches the documentation metadata to the route, see Mux.Doc.
+func (rh *RouteHandle) Doc(doc RouteDoc) *RouteHandle {
+	for _, method := range rh.methods {
+		rh.mx.Doc(method, rh.pattern, doc)
+	}
+	return rh
+}
+
+// Accepts restricts the request Content-Types accepted by the route, see
+// Mux.Accepts.
+func (rh *RouteHandle) Accepts(contentTypes ...string) *RouteHandle {
+	for _, method := range rh.methods {
+		rh.mx.Accepts(method, rh.pattern, contentTypes...)
+	}
+	return rh
+}
+
+// Stack wraps the route with the middlewares of the named stacks, in order,
+// which run before the inline middlewares the route was registered with. It
+// panics if a stack isn't defined, see Mux.Stack.
+func (rh *RouteHandle) Stack(names ...string) *RouteHandle {
+	mws := rh.mx.stackMiddlewares(names)
+	for _, method := range rh.methods {
+		for _, ep := range rh.mx.methodEndpoints("Stack", method, rh.pattern) {
+			h := ep.handler
+			// Keep reporting the middlewares of the route as a single chain
+			if ch, ok := h.(*ChainHandler); ok {
+				ep.handler = &ChainHandler{ch.Endpoint, chain(mws, ch), append(mws[:len(mws):len(mws)], ch.Middlewares...)}
+			} else {
+				ep.handler = mws.Handler(h)
+			}
+		}
+	}
+	return rh
+}
+
 // MethodFunc adds the route `pattern` that matches `method` http method to
 // execute the `handlerFn` http.HandlerFunc.
 func (mx *Mux) MethodFunc(method, pattern string, handlerFn http.HandlerFunc) {
 	mx.MethodStd(method, pattern, handlerFn)
 }
 
+// MatchFunc adds the route `pattern` that matches all http methods to execute
+// the `handlerFn` http.HandlerFunc, as long as `match` returns true for the
+// request, ie. to route feature flags or header based API versions to
+// different handlers on the same path. The predicates of a pattern are
+// evaluated in registration order, for any http method, and when none
+// matches the request is served by the route registered with Handle or Method
+// on the pattern for its method, if any. Otherwise it's answered with a 405
+// when routes are registered for other methods, or by the not found handler.
+func (mx *Mux) MatchFunc(match func(r *http.Request) bool, pattern string, handlerFn http.HandlerFunc) {
+	if match == nil {
+		panic(fmt.Sprintf("clover: attempting to MatchFunc() a nil predicate on '%s'", pattern))
+	}
+	if len(pattern) == 0 || pattern[0] != '/' {
+		panic(fmt.Sprintf("clover: routing pattern must begin with '/' in '%s'", pattern))
+	}
+	pattern = mx.routePattern(pattern)
+	patCheckRegexps(pattern)
+
+	if !mx.inline && mx.handler == nil {
+		mx.updateRouteHandler()
+	}
+	var h http.Handler = handlerFn
+	if mx.inline {
+		mx.handler = http.HandlerFunc(mx.routeHTTP)
+		h = Chain(mx.middlewares...).Handler(handlerFn)
+	}
+
+	mx.insertMatchFallback(pattern)
+
+	root := mx.root()
+	if root.matchRoutes == nil {
+		root.matchRoutes = map[string][]matchRoute{}
+	}
+	root.matchRoutes[pattern] = append(root.matchRoutes[pattern], matchRoute{match, h})
+	mx.notifyRoute(mALL, pattern, h)
+}
+
+// insertMatchFallback makes the pattern routable for every http method
+// without a route, so the predicates of MatchFunc are evaluated whatever the
+// order routes are registered in. When none matches, the fallback responds
+// as if there were no predicates: 405 if routes are registered for other
+// methods, 404 otherwise.
+func (mx *Mux) insertMatchFallback(pattern string) {
+	var n *node
+	fallback := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var allowed []methodTyp
+		for mt, ep := range n.endpoints {
+			if mt != mALL && ep.explicit {
+				allowed = append(allowed, mt)
+			}
+		}
+		if len(allowed) == 0 {
+			mx.NotFoundHandler().ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Allow", mx.root().allowHeader(allowed))
+		mx.MethodNotAllowedHandler().ServeHTTP(w, r)
+	})
+
+	eps := mx.patternEndpoints(pattern)
+	if eps == nil {
+		n = mx.insertEndpoint(mALL, pattern, fallback)
+		n.endpoints[mALL].explicit = false
+		return
+	}
+	for _, mt := range methodMap {
+		if ep := eps[mt]; ep == nil || ep.handler == nil {
+			n = mx.insertEndpoint(mt, pattern, fallback)
+			n.endpoints[mt].explicit = false
+		}
+	}
+}
+
 // NotFound sets a custom http.HandlerFunc for routing paths that could
 // not be found. The default 404 handler is `http.NotFound`.
 func (mx *Mux) NotFound(handlerFn http.HandlerFunc) {
@@ -174,6 +430,32 @@ func (mx *Mux) NotFound(handlerFn http.HandlerFunc) {
 	})
 }
 
+// Fallback sets a HandlerFunc invoked for the routing paths that could not be
+// found, instead of the NotFound handler, ie. to proxy unknown paths to an
+// upstream service with render.Proxy. Unlike NotFound it doesn't imply a 404,
+// the fallback decides how to respond and may still render one.
+func (mx *Mux) Fallback(handler HandlerFunc) {
+	m := mx
+	var h http.Handler = handler
+	if mx.inline && mx.parent != nil {
+		m = mx.parent
+		h = Chain(mx.middlewares...).Handler(h)
+	}
+
+	m.fallbackHandler = h
+	m.updateSubRoutes(func(subMux *Mux) {
+		if subMux.fallbackHandler == nil {
+			subMux.fallbackHandler = h
+		}
+	})
+}
+
+// NotFoundFunc sets a custom HandlerFunc for routing paths that could
+// not be found, allowing the 404 response to be built with a render.Render.
+func (mx *Mux) NotFoundFunc(handler HandlerFunc) {
+	mx.NotFound(handler.ServeHTTP)
+}
+
 // MethodNotAllowed sets a custom http.HandlerFunc for routing paths where the
 // method is unresolved. The default handler returns a 405 with an empty body.
 func (mx *Mux) MethodNotAllowed(handlerFn http.HandlerFunc) {
@@ -194,7 +476,77 @@ func (mx *Mux) MethodNotAllowed(handlerFn http.HandlerFunc) {
 	})
 }
 
-// With adds inline middlewares for an endpoint handler.
+// AllowOrder sets the order of the methods listed by the Allow header of the
+// 405 responses, for routers whose clients expect a specific one. The methods
+// of the route missing from `methods` follow in the default order: GET, HEAD,
+// POST, PUT, PATCH, DELETE, OPTIONS, CONNECT, TRACE, then the custom methods
+// sorted by name.
+func (mx *Mux) AllowOrder(methods ...string) {
+	order := make([]string, len(methods))
+	for i, m := range methods {
+		order[i] = strings.ToUpper(m)
+	}
+
+	m := mx.root()
+	m.allowOrder = order
+	m.updateSubRoutes(func(subMux *Mux) {
+		if subMux.allowOrder == nil {
+			subMux.AllowOrder(order...)
+		}
+	})
+}
+
+// defaultAllowOrder is the order of the methods listed by the Allow header.
+var defaultAllowOrder = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace,
+}
+
+// allowHeader returns the Allow header value listing the methods, in the
+// AllowOrder of the mux and the default order otherwise, without duplicates.
+func (mx *Mux) allowHeader(methods []methodTyp) string {
+	rank := func(method string) int {
+		for i, m := range mx.allowOrder {
+			if m == method {
+				return i
+			}
+		}
+		for i, m := range defaultAllowOrder {
+			if m == method {
+				return len(mx.allowOrder) + i
+			}
+		}
+		return len(mx.allowOrder) + len(defaultAllowOrder)
+	}
+
+	names := make([]string, 0, len(methods))
+	seen := make(map[string]struct{}, len(methods))
+	for _, mt := range methods {
+		name := methodTypString(mt)
+		if _, ok := seen[name]; name == "" || ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := rank(names[i]), rank(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+	return strings.Join(names, ", ")
+}
+
+// MethodNotAllowedFunc sets a custom HandlerFunc for routing paths where the
+// method is unresolved, allowing the 405 response to be built with a render.Render.
+func (mx *Mux) MethodNotAllowedFunc(handler HandlerFunc) {
+	mx.MethodNotAllowed(handler.ServeHTTP)
+}
+
+// With adds inline middlewares for an endpoint handler, which run after the
+// middlewares of the Mux, in registration order, see Use.
 func (mx *Mux) With(middlewares ...func(http.Handler) http.Handler) Router {
 	// Similarly as in handle(), we must build the mux handler once additional
 	// middleware registration isn't allowed for this stack, like now.
@@ -211,13 +563,61 @@ func (mx *Mux) With(middlewares ...func(http.Handler) http.Handler) Router {
 	mws = append(mws, middlewares...)
 
 	im := &Mux{
-		pool: mx.pool, inline: true, parent: mx, tree: mx.tree, middlewares: mws,
+		pool: mx.pool, inline: true, parent: mx, tree: mx.tree, static: mx.static, middlewares: mws,
 		notFoundHandler: mx.notFoundHandler, methodNotAllowedHandler: mx.methodNotAllowedHandler,
+		fallbackHandler: mx.fallbackHandler,
 	}
 
 	return im
 }
 
+// UseStack defines the named middleware stack `name`, ie. "public", "authed"
+// or "admin", which is then applied to routes with Stack rather than nesting
+// Group and With calls. Subrouters created with Route and PathPrefix inherit
+// the stacks defined up to then.
+//
+//	r.UseStack("authed", middleware.BasicAuth("api", creds), middleware.NoCache)
+//	r.Stack("authed").MethodFunc("GET", "/account", account)
+func (mx *Mux) UseStack(name string, middlewares ...func(http.Handler) http.Handler) {
+	m := mx.root()
+	if m.stacks == nil {
+		m.stacks = map[string]Middlewares{}
+	}
+	m.stacks[name] = append(Middlewares(nil), middlewares...)
+}
+
+// Stack returns an inline-Mux with the middlewares of the named stacks, in
+// order, just like With. It panics if a stack isn't defined. Routes added
+// with Register can also be given stacks from their handle.
+func (mx *Mux) Stack(names ...string) Router {
+	return mx.With(mx.stackMiddlewares(names)...)
+}
+
+// stackMiddlewares returns the middlewares of the named stacks, in order. It
+// panics if a stack isn't defined.
+func (mx *Mux) stackMiddlewares(names []string) Middlewares {
+	stacks := mx.root().stacks
+	var mws Middlewares
+	for _, name := range names {
+		stack, ok := stacks[name]
+		if !ok {
+			panic(fmt.Sprintf("clover: attempting to use an undefined middleware stack '%s'", name))
+		}
+		mws = append(mws, stack...)
+	}
+	return mws
+}
+
+// inheritStacks copies the middleware stacks of mx to a new subrouter.
+func (mx *Mux) inheritStacks(subRouter *Mux) {
+	if stacks := mx.root().stacks; stacks != nil {
+		subRouter.stacks = make(map[string]Middlewares, len(stacks))
+		for name, stack := range stacks {
+			subRouter.stacks[name] = stack
+		}
+	}
+}
+
 // Group creates a new inline-Mux with a fresh middleware stack. It's useful
 // for a group of handlers along the same routing path that use an additional
 // set of middlewares. See _examples/.
@@ -237,11 +637,208 @@ func (mx *Mux) Route(pattern string, fn func(r Router)) Router {
 		panic(fmt.Sprintf("clover: attempting to Route() a nil subrouter on '%s'", pattern))
 	}
 	subRouter := newMux()
+	subRouter.colonSyntax = mx.root().colonSyntax
+	mx.inheritStacks(subRouter)
 	fn(subRouter)
 	mx.Mount(pattern, subRouter)
+	mx.forwardRouteHooks(pattern, subRouter)
 	return subRouter
 }
 
+// Doc attaches the documentation metadata to the route registered for the
+// `method` http method and `pattern`, or to all its methods when `method` is
+// "*", which is then exposed by Routes(). It panics if there is no such
+// route. Routes added with Register can be documented from their handle.
+//
+//	r.MethodFunc("GET", "/users/{id}", getUser)
+//	r.Doc("GET", "/users/{id}", clover.RouteDoc{Summary: "Get a user"})
+func (mx *Mux) Doc(method, pattern string, doc RouteDoc) {
+	pattern = mx.routePattern(pattern)
+	eps := mx.patternEndpoints(pattern)
+	if method == "*" {
+		for _, ep := range eps {
+			ep.doc = &doc
+		}
+	} else if ep := eps[methodMap[strings.ToUpper(method)]]; ep != nil {
+		ep.doc = &doc
+	} else {
+		eps = nil
+	}
+	if eps == nil {
+		panic(fmt.Sprintf("clover: attempting to Doc() a missing route '%s %s'", method, pattern))
+	}
+}
+
+// Accepts restricts the request Content-Types accepted by the route already
+// registered for the `method` http method and `pattern`, or by all its
+// methods when `method` is "*", keeping the constraint next to the route
+// definition rather than in a middleware.AllowContentType group. Requests
+// with a body of another media type are answered with a 415 Unsupported
+// Media Type, while requests without a body are let through. It panics if
+// there is no such route. Routes added with Register can be restricted from
+// their handle.
+//
+//	r.Method("POST", "/users", createUser)
+//	r.Accepts("POST", "/users", "application/json")
+func (mx *Mux) Accepts(method, pattern string, contentTypes ...string) {
+	eps := mx.methodEndpoints("Accepts", method, pattern)
+
+	allowed := make(map[string]struct{}, len(contentTypes))
+	for _, ctype := range contentTypes {
+		allowed[strings.TrimSpace(strings.ToLower(ctype))] = struct{}{}
+	}
+	for _, ep := range eps {
+		next := ep.handler
+		var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength != 0 {
+				ctype, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
+				if _, ok := allowed[strings.TrimSpace(strings.ToLower(ctype))]; !ok {
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+		// Keep reporting the inline middlewares of the route
+		if ch, ok := next.(*ChainHandler); ok {
+			h = &ChainHandler{ch.Endpoint, h, ch.Middlewares}
+		}
+		ep.handler = h
+	}
+}
+
+// RouteIf calls Route when cond is true, ie. to mount feature flagged
+// subrouters without surrounding if blocks. It returns the subrouter, or nil
+// when cond is false.
+func (mx *Mux) RouteIf(cond bool, pattern string, fn func(r Router)) Router {
+	if !cond {
+		return nil
+	}
+	return mx.Route(pattern, fn)
+}
+
+// UseIf calls Use with the middlewares when cond is true.
+func (mx *Mux) UseIf(cond bool, middlewares ...func(http.Handler) http.Handler) {
+	if cond {
+		mx.Use(middlewares...)
+	}
+}
+
+// AsMux returns the Mux itself, so the concrete sub-Router returned by
+// Route or PathPrefix can be inspected without serving requests. Routes()
+// on a sub-Router only lists the routes registered on it, while inline
+// routers from With and Group share the routing tree of their parent.
+func (mx *Mux) AsMux() *Mux {
+	return mx
+}
+
+// Alias registers the `aliases` patterns to serve the routes already defined
+// on the `canonical` pattern, sharing their handlers and middleware chains,
+// ie. to serve both /health and /healthz. It panics if no route is defined
+// on the `canonical` pattern.
+func (mx *Mux) Alias(canonical string, aliases ...string) {
+	canonical = mx.routePattern(canonical)
+	eps := mx.patternEndpoints(canonical)
+	if eps == nil {
+		panic(fmt.Sprintf("clover: attempting to Alias() a missing route '%s'", canonical))
+	}
+
+	for _, pattern := range aliases {
+		if len(pattern) == 0 || pattern[0] != '/' {
+			panic(fmt.Sprintf("clover: routing pattern must begin with '/' in '%s'", pattern))
+		}
+		pattern = mx.routePattern(pattern)
+		patCheckRegexps(pattern)
+
+		// Routes matching all methods go first, as they're overridden by the
+		// routes matching a single method.
+		if ep := eps[mALL]; ep != nil {
+			mx.insertRoute(mALL, pattern, ep.handler)
+		}
+		for mt, ep := range eps {
+			if mt != mALL && (eps[mALL] == nil || !sameHandler(ep.handler, eps[mALL].handler)) {
+				mx.insertRoute(mt, pattern, ep.handler)
+			}
+		}
+	}
+}
+
+// patternEndpoints returns the endpoints registered on the routing tree for
+// the pattern, or nil if there are none.
+func (mx *Mux) patternEndpoints(pattern string) endpoints {
+	var eps endpoints
+	mx.tree.walk(func(e endpoints, _ Routes) bool {
+		for mt, ep := range e {
+			if mt&mSTUB != mSTUB && ep.pattern == pattern && ep.handler != nil {
+				if eps == nil {
+					eps = endpoints{}
+				}
+				eps[mt] = ep
+			}
+		}
+		return eps != nil
+	})
+	return eps
+}
+
+// methodEndpoints returns the endpoints registered for the `method` http
+// method and `pattern`, or for all its methods when `method` is "*". It
+// panics naming the `fn` caller if there is no such route.
+func (mx *Mux) methodEndpoints(fn, method, pattern string) endpoints {
+	pattern = mx.routePattern(pattern)
+	eps := mx.patternEndpoints(pattern)
+	if method != "*" {
+		mt := methodMap[strings.ToUpper(method)]
+		if ep := eps[mt]; ep != nil {
+			eps = endpoints{mt: ep}
+		} else {
+			eps = nil
+		}
+	}
+	if eps == nil {
+		panic(fmt.Sprintf("clover: attempting to %s() a missing route '%s %s'", fn, method, pattern))
+	}
+	return eps
+}
+
+// sameHandler reports whether a and b are the same handler, comparing func
+// handlers by their code pointer.
+func sameHandler(a, b http.Handler) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if ta.Kind() == reflect.Func {
+		return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+	}
+	return ta.Comparable() && a == b
+}
+
+// PathPrefix creates a new Mux with a fresh middleware stack and mounts it
+// along the `pattern` as a subrouter, same as Route, but returns it for routes
+// to be added imperatively rather than through a callback.
+func (mx *Mux) PathPrefix(pattern string) Router {
+	subRouter := newMux()
+	subRouter.colonSyntax = mx.root().colonSyntax
+	mx.inheritStacks(subRouter)
+	mx.Mount(pattern, subRouter)
+	mx.forwardRouteHooks(pattern, subRouter)
+	return subRouter
+}
+
+// forwardRouteHooks reports the routes registered on the subrouter mounted
+// along `pattern` once it's mounted, ie. the ones of a PathPrefix router, to
+// the OnRoute callbacks of mx with their full pattern.
+func (mx *Mux) forwardRouteHooks(pattern string, subRouter *Mux) {
+	root := mx.root()
+	prefix := strings.TrimSuffix(mx.routePattern(pattern), "/")
+	subRouter.routeHooks = append(subRouter.routeHooks, func(method, route string, h http.Handler) {
+		for _, fn := range root.routeHooks {
+			fn(method, prefix+route, h)
+		}
+	})
+}
+
 // Mount attaches another http.Handler or clover Router as a subrouter along a routing
 // path. It's very useful to split up a large API as many independent routers and
 // compose them as a single service using Mount. See _examples/.
@@ -253,6 +850,7 @@ func (mx *Mux) Mount(pattern string, handler http.Handler) {
 	if handler == nil {
 		panic(fmt.Sprintf("clover: attempting to Mount() a nil handler on '%s'", pattern))
 	}
+	pattern = mx.routePattern(pattern)
 
 	// Provide runtime safety for ensuring a pattern isn't mounted on an existing
 	// routing pattern.
@@ -261,13 +859,19 @@ func (mx *Mux) Mount(pattern string, handler http.Handler) {
 	}
 
 	// Assign sub-Router's with the parent not found & method not allowed handler if not specified.
-	subr, ok := handler.(*Mux)
+	subr, ok := asMux(handler)
 	if ok && subr.notFoundHandler == nil && mx.notFoundHandler != nil {
 		subr.NotFound(mx.notFoundHandler)
 	}
+	if ok && subr.fallbackHandler == nil && mx.fallbackHandler != nil {
+		subr.fallbackHandler = mx.fallbackHandler
+	}
 	if ok && subr.methodNotAllowedHandler == nil && mx.methodNotAllowedHandler != nil {
 		subr.MethodNotAllowed(mx.methodNotAllowedHandler)
 	}
+	if ok && subr.allowOrder == nil && mx.root().allowOrder != nil {
+		subr.AllowOrder(mx.root().allowOrder...)
+	}
 
 	mountHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		rctx := RouteContext(r.Context())
@@ -299,6 +903,15 @@ func (mx *Mux) Mount(pattern string, handler http.Handler) {
 
 	if subroutes != nil {
 		n.subroutes = subroutes
+		if hooks := mx.root().routeHooks; len(hooks) > 0 {
+			prefix := strings.TrimSuffix(pattern, "/")
+			Walk(subroutes, func(method, route string, handler http.Handler, _ ...func(http.Handler) http.Handler) error {
+				for _, fn := range hooks {
+					fn(method, prefix+route, handler)
+				}
+				return nil
+			})
+		}
 	}
 }
 
@@ -359,6 +972,8 @@ func (mx *Mux) handle(method methodTyp, pattern string, handler http.Handler) *n
 	if len(pattern) == 0 || pattern[0] != '/' {
 		panic(fmt.Sprintf("clover: routing pattern must begin with '/' in '%s'", pattern))
 	}
+	pattern = mx.routePattern(pattern)
+	patCheckRegexps(pattern)
 
 	// Build the computed routing handler for this routing pattern.
 	if !mx.inline && mx.handler == nil {
@@ -375,7 +990,40 @@ func (mx *Mux) handle(method methodTyp, pattern string, handler http.Handler) *n
 	}
 
 	// Add the endpoint to the tree and return the node
-	return mx.tree.InsertRoute(method, pattern, h)
+	return mx.insertRoute(method, pattern, h)
+}
+
+// insertRoute adds an endpoint handler to the routing tree and notifies the
+// route hooks, except for the stubs registered by Mount.
+func (mx *Mux) insertRoute(method methodTyp, pattern string, h http.Handler) *node {
+	n := mx.insertEndpoint(method, pattern, h)
+	if method&mSTUB != mSTUB {
+		mx.notifyRoute(method, pattern, h)
+	}
+	return n
+}
+
+// insertEndpoint adds an endpoint handler to the routing tree and indexes
+// its pattern.
+func (mx *Mux) insertEndpoint(method methodTyp, pattern string, h http.Handler) *node {
+	n := mx.tree.InsertRoute(method, pattern, h)
+	mx.static.add(pattern, n)
+	return n
+}
+
+// notifyRoute invokes the OnRoute callbacks for a registered route.
+func (mx *Mux) notifyRoute(method methodTyp, pattern string, h http.Handler) {
+	hooks := mx.root().routeHooks
+	if len(hooks) == 0 {
+		return
+	}
+	m := "*"
+	if method != mALL {
+		m = methodTypString(method)
+	}
+	for _, fn := range hooks {
+		fn(m, pattern, h)
+	}
 }
 
 // routeHTTP routes a http.Request through the Mux routing tree to serve
@@ -387,7 +1035,7 @@ func (mx *Mux) routeHTTP(w http.ResponseWriter, r *http.Request) {
 	// The request routing path
 	routePath := rctx.RoutePath
 	if routePath == "" {
-		if r.URL.RawPath != "" {
+		if r.URL.RawPath != "" && !mx.root().decodedPath {
 			routePath = r.URL.RawPath
 		} else {
 			routePath = r.URL.Path
@@ -396,6 +1044,9 @@ func (mx *Mux) routeHTTP(w http.ResponseWriter, r *http.Request) {
 			routePath = "/"
 		}
 	}
+	// Record the routing path, so it's still available to the not found
+	// and method not allowed handlers
+	rctx.attemptedPath = routePath
 
 	// Check if method is supported by clover
 	if rctx.RouteMethod == "" {
@@ -407,18 +1058,70 @@ func (mx *Mux) routeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Record the middlewares of the router, which already ran
+	if !mx.inline {
+		rctx.routeMiddlewares = append(rctx.routeMiddlewares, mx.middlewares...)
+	}
+
 	// Find the route
-	if _, _, h := mx.tree.FindRoute(rctx, method, routePath); h != nil {
+	if h := mx.findHandler(rctx, method, routePath); h != nil {
+		if routes := mx.matchRoutes[rctx.routePattern]; len(routes) > 0 {
+			for _, route := range routes {
+				if route.match(r) {
+					h = route.handler
+					break
+				}
+			}
+		}
+		// Inline middlewares are chained onto the endpoint handler
+		if ch, ok := h.(*ChainHandler); ok {
+			rctx.routeMiddlewares = append(rctx.routeMiddlewares, ch.Middlewares...)
+		}
 		h.ServeHTTP(w, r)
 		return
 	}
 	if rctx.methodNotAllowed {
+		if len(rctx.methodsAllowed) > 0 {
+			w.Header().Set("Allow", mx.root().allowHeader(rctx.methodsAllowed))
+		}
 		mx.MethodNotAllowedHandler().ServeHTTP(w, r)
+	} else if mx.fallbackHandler != nil {
+		mx.fallbackHandler.ServeHTTP(w, r)
 	} else {
 		mx.NotFoundHandler().ServeHTTP(w, r)
 	}
 }
 
+// findHandler searches for the handler of the routing path, using a map lookup
+// as long as only static routes have been registered on the mux, and walking
+// the routing tree otherwise.
+func (mx *Mux) findHandler(rctx *Context, method methodTyp, path string) http.Handler {
+	if mx.static.dynamic {
+		_, _, h := mx.tree.FindRoute(rctx, method, path)
+		return h
+	}
+
+	rctx.routePattern = ""
+	rctx.routeParams.Keys = rctx.routeParams.Keys[:0]
+	rctx.routeParams.Values = rctx.routeParams.Values[:0]
+
+	n := mx.static.nodes[path]
+	if n == nil {
+		return nil
+	}
+	ep := n.endpoints[method]
+	if ep == nil || ep.handler == nil {
+		rctx.methodNotAllowed = true
+		rctx.methodsAllowed = n.endpoints.methods(rctx.methodsAllowed)
+		return nil
+	}
+	if ep.pattern != "" {
+		rctx.routePattern = ep.pattern
+		rctx.RoutePatterns = append(rctx.RoutePatterns, rctx.routePattern)
+	}
+	return ep.handler
+}
+
 func (mx *Mux) nextRoutePath(rctx *Context) string {
 	routePath := "/"
 	nx := len(rctx.routeParams.Keys) - 1 // index of last param in list
@@ -428,10 +1131,61 @@ func (mx *Mux) nextRoutePath(rctx *Context) string {
 	return routePath
 }
 
+// routePattern translates the `:param` segments of a routing pattern to the
+// `{param}` form when the ColonSyntax mode is enabled.
+func (mx *Mux) routePattern(pattern string) string {
+	if !mx.root().colonSyntax || !strings.Contains(pattern, "/:") {
+		return pattern
+	}
+
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		case c == ':' && depth == 0 && i > 0 && pattern[i-1] == '/':
+			end := strings.IndexByte(pattern[i:], '/')
+			if end < 0 {
+				end = len(pattern) - i
+			}
+			b.WriteString("{" + pattern[i+1:i+end] + "}")
+			i += end - 1
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// root returns the Mux an inline-Mux was created from, or mx itself.
+func (mx *Mux) root() *Mux {
+	m := mx
+	for m.inline && m.parent != nil {
+		m = m.parent
+	}
+	return m
+}
+
+// asMux returns the Mux backing a subrouter, which may either be a *Mux
+// or a *Clover.
+func asMux(h interface{}) (*Mux, bool) {
+	switch m := h.(type) {
+	case *Mux:
+		return m, true
+	case *Clover:
+		return m.Mux, m.Mux != nil
+	}
+	return nil, false
+}
+
 // Recursively update data on cloverld routers.
 func (mx *Mux) updateSubRoutes(fn func(subMux *Mux)) {
 	for _, r := range mx.tree.routes() {
-		subMux, ok := r.SubRoutes.(*Mux)
+		subMux, ok := asMux(r.SubRoutes)
 		if !ok {
 			continue
 		}
@@ -447,6 +1201,32 @@ func (mx *Mux) updateRouteHandler() {
 	mx.handler = chain(mx.middlewares, http.HandlerFunc(mx.routeHTTP))
 }
 
+// staticIndex maps the patterns of a routing tree to their leaf nodes, for as
+// long as the tree only holds static routes. Routers with a small set of static
+// paths are then served with a map lookup instead of a tree walk.
+type staticIndex struct {
+	nodes   map[string]*node
+	dynamic bool
+}
+
+func newStaticIndex() *staticIndex {
+	return &staticIndex{nodes: map[string]*node{}}
+}
+
+// add records the leaf node of a pattern, or falls back to the tree for
+// good once a param, regexp or wildcard pattern is registered.
+func (s *staticIndex) add(pattern string, n *node) {
+	if s.dynamic {
+		return
+	}
+	if strings.ContainsAny(pattern, "{*") {
+		s.dynamic = true
+		s.nodes = nil
+		return
+	}
+	s.nodes[pattern] = n
+}
+
 // methodNotAllowedHandler is a helper function to respond with a 405,
 // method not allowed.
 func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
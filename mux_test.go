@@ -9,9 +9,14 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/goclover/clover/render"
 )
 
 func TestMuxBasic(t *testing.T) {
@@ -110,8 +115,7 @@ func TestMuxBasic(t *testing.T) {
 
 	m.MethodFunc("HEAD", "/ping", headPing)
 	m.MethodFunc("POST", "/ping", createPing)
-	m.MethodFunc("GET", "/ping/{id}", pingWoop)
-	m.MethodFunc("GET", "/ping/{id}", pingOne) // expected to overwrite to pingOne handler
+	m.MethodFunc("GET", "/ping/{id}", pingOne)
 	m.MethodFunc("GET", "/ping/{iidd}/woop", pingWoop)
 	m.HandleFunc("/admin/*", catchAll)
 	// m.MethodFunc("POST","/admin/*", catchAll)
@@ -458,6 +462,131 @@ func TestMuxNestedMethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestMuxAllowHeader(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := NewRouter()
+	for _, m := range []string{"OPTIONS", "DELETE", "PATCH", "PUT", "POST", "HEAD", "GET"} {
+		r.MethodFunc(m, "/things/{id}", h)
+	}
+	r.MethodFunc("PUT", "/static", h)
+	r.MethodFunc("GET", "/static", h)
+	r.Route("/sub", func(r Router) {
+		r.MethodFunc("POST", "/", h)
+		r.MethodFunc("GET", "/", h)
+	})
+
+	for i := 0; i < 10; i++ {
+		resp, _ := testHandler(t, r, "TRACE", "/things/1", nil)
+		if resp.StatusCode != 405 {
+			t.Fatalf("expected 405, got %d", resp.StatusCode)
+		}
+		if allow := resp.Header.Get("Allow"); allow != "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS" {
+			t.Fatalf("unexpected Allow header: %q", allow)
+		}
+	}
+	if resp, _ := testHandler(t, r, "POST", "/static", nil); resp.Header.Get("Allow") != "GET, PUT" {
+		t.Fatalf("unexpected Allow header: %q", resp.Header.Get("Allow"))
+	}
+
+	r.AllowOrder("options", "POST")
+	if resp, _ := testHandler(t, r, "TRACE", "/things/1", nil); resp.Header.Get("Allow") != "OPTIONS, POST, GET, HEAD, PUT, PATCH, DELETE" {
+		t.Fatalf("unexpected Allow header: %q", resp.Header.Get("Allow"))
+	}
+	if resp, _ := testHandler(t, r, "PUT", "/sub/", nil); resp.Header.Get("Allow") != "POST, GET" {
+		t.Fatalf("unexpected Allow header: %q", resp.Header.Get("Allow"))
+	}
+}
+
+func TestMuxNotFoundFunc(t *testing.T) {
+	r := New()
+	r.MethodFunc("GET", "/hi", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("bye"))
+	})
+	r.NotFoundFunc(func(ctx context.Context, r *http.Request) render.Render {
+		rr := render.JSON(map[string]string{"error": "not found"})
+		rr.Status = http.StatusNotFound
+		return rr
+	})
+	r.MethodNotAllowedFunc(func(ctx context.Context, r *http.Request) render.Render {
+		rr := render.Text("nope")
+		rr.Status = http.StatusMethodNotAllowed
+		return rr
+	})
+
+	sr := New()
+	sr.MethodFunc("GET", "/sub", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("sub"))
+	})
+	r.Mount("/admin", sr)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, body := testRequest(t, ts, "GET", "/nothing-here", nil)
+	if resp.StatusCode != 404 || body != `{"error":"not found"}` {
+		t.Fatalf("%d %s", resp.StatusCode, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+	if resp, body := testRequest(t, ts, "GET", "/admin/nope", nil); resp.StatusCode != 404 || body != `{"error":"not found"}` {
+		t.Fatalf("%d %s", resp.StatusCode, body)
+	}
+	if resp, body := testRequest(t, ts, "POST", "/hi", nil); resp.StatusCode != 405 || body != "nope" {
+		t.Fatalf("%d %s", resp.StatusCode, body)
+	}
+}
+
+func TestMuxNotFoundRoutePath(t *testing.T) {
+	r := New()
+	r.MethodFunc("GET", "/hi", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("bye"))
+	})
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte(fmt.Sprintf("%s %s not found", r.Method, RoutePath(r))))
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(405)
+		w.Write([]byte(fmt.Sprintf("%s %s not allowed", r.Method, RoutePath(r))))
+	})
+	r.Route("/admin", func(r Router) {
+		r.MethodFunc("GET", "/users", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	if _, body := testRequest(t, ts, "GET", "/nothing-here", nil); body != "GET /nothing-here not found" {
+		t.Fatalf(body)
+	}
+	if _, body := testRequest(t, ts, "POST", "/hi", nil); body != "POST /hi not allowed" {
+		t.Fatalf(body)
+	}
+	if _, body := testRequest(t, ts, "GET", "/admin/nope", nil); body != "GET /nope not found" {
+		t.Fatalf(body)
+	}
+}
+
+func TestMuxNestedWithoutMount(t *testing.T) {
+	sub := New()
+	sub.MethodFunc("GET", "/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("users"))
+	})
+
+	r := New()
+	r.MethodFunc("GET", "/hi", func(w http.ResponseWriter, r *http.Request) {})
+	r.HandleStd("/api/*", http.StripPrefix("/api", sub))
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	if resp, body := testRequest(t, ts, "GET", "/api/users", nil); resp.StatusCode != 200 || body != "users" {
+		t.Fatalf("%d %s", resp.StatusCode, body)
+	}
+}
+
 func TestMuxComplicatedNotFound(t *testing.T) {
 	decorateRouter := func(r *Mux) {
 		// Root router with groups
@@ -1161,186 +1290,943 @@ func TestMuxSubroutes(t *testing.T) {
 
 }
 
-func TestSingleHandler(t *testing.T) {
-	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		name := URLParam(r, "name")
-		w.Write([]byte("hi " + name))
-	})
-
-	r, _ := http.NewRequest("GET", "/", nil)
-	rctx := NewRouteContext()
-	r = r.WithContext(context.WithValue(r.Context(), RouteCtxKey, rctx))
-	rctx.URLParams.Add("name", "joe")
+func TestMuxSubrouterRoutes(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
 
-	w := httptest.NewRecorder()
-	h.ServeHTTP(w, r)
+	r := NewRouter()
+	r.MethodFunc("GET", "/", h)
+	sr := r.Route("/users", func(r Router) {
+		r.MethodFunc("GET", "/", h)
+		r.MethodFunc("GET", "/{id}", h)
+	})
+	r.Route("/posts", func(r Router) {
+		r.MethodFunc("GET", "/", h)
+	})
 
-	body := w.Body.String()
-	expected := "hi joe"
-	if body != expected {
-		t.Fatalf("expected:%s got:%s", expected, body)
+	sub := sr.AsMux()
+	if sub == r.AsMux() {
+		t.Fatal("expected the subrouter to have its own mux")
+	}
+	var patterns []string
+	for _, route := range sub.Routes() {
+		patterns = append(patterns, route.Pattern)
+	}
+	if strings.Join(patterns, ",") != "/,/{id}" {
+		t.Fatalf("expected only the subrouter routes, got %v", patterns)
+	}
+	if len(r.Routes()) != 3 {
+		t.Fatalf("expected 3 routes on the parent router, got %d", len(r.Routes()))
 	}
 }
 
-// TODO: a Router wrapper test..
-//
-// type ACLMux struct {
-// 	*Mux
-// 	XX string
-// }
-//
-// func NewACLMux() *ACLMux {
-// 	return &ACLMux{Mux: New(), XX: "hihi"}
-// }
-//
-// // TODO: this should be supported...
-// func TestWoot(t *testing.T) {
-// 	var r Router = New()
-//
-// 	var r2 Router = NewACLMux() //New()
-// 	r2.MethodFunc("GET","/hi", func(w http.ResponseWriter, r *http.Request) {
-// 		w.Write([]byte("hi"))
-// 	})
-//
-// 	r.Mount("/", r2)
-// }
-
-func TestServeHTTPExistingContext(t *testing.T) {
-	r := New()
-	r.MethodFunc("GET", "/hi", func(w http.ResponseWriter, r *http.Request) {
-		s, _ := r.Context().Value(ctxKey{"testCtx"}).(string)
-		w.Write([]byte(s))
-	})
+func TestMuxPathPrefix(t *testing.T) {
+	r := NewRouter()
 	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
-		s, _ := r.Context().Value(ctxKey{"testCtx"}).(string)
 		w.WriteHeader(404)
-		w.Write([]byte(s))
+		w.Write([]byte("root 404"))
 	})
 
-	testcases := []struct {
-		Ctx            context.Context
-		Method         string
-		Path           string
-		ExpectedBody   string
-		ExpectedStatus int
-	}{
-		{
-			Method:         "GET",
-			Path:           "/hi",
-			Ctx:            context.WithValue(context.Background(), ctxKey{"testCtx"}, "hi ctx"),
-			ExpectedStatus: 200,
-			ExpectedBody:   "hi ctx",
-		},
-		{
-			Method:         "GET",
-			Path:           "/hello",
-			Ctx:            context.WithValue(context.Background(), ctxKey{"testCtx"}, "nothing here ctx"),
-			ExpectedStatus: 404,
-			ExpectedBody:   "nothing here ctx",
-		},
-	}
+	api := r.PathPrefix("/api")
+	api.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-API", "1")
+			next.ServeHTTP(w, r)
+		})
+	})
+	api.MethodFunc("GET", "/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("user " + URLParam(r, "id") + " " + RouteContext(r.Context()).RoutePattern()))
+	})
+	api.MethodFunc("GET", "/flagged", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("flagged"))
+	})
 
-	for _, tc := range testcases {
-		resp := httptest.NewRecorder()
-		req, err := http.NewRequest(tc.Method, tc.Path, nil)
-		if err != nil {
-			t.Fatalf("%v", err)
-		}
-		req = req.WithContext(tc.Ctx)
-		r.ServeHTTP(resp, req)
-		b, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			t.Fatalf("%v", err)
-		}
-		if resp.Code != tc.ExpectedStatus {
-			t.Fatalf("%v != %v", tc.ExpectedStatus, resp.Code)
-		}
-		if string(b) != tc.ExpectedBody {
-			t.Fatalf("%s != %s", tc.ExpectedBody, b)
-		}
+	resp, body := testHandler(t, r, "GET", "/api/users/7", nil)
+	if body != "user 7 /api/users/{id}" || resp.Header.Get("X-API") != "1" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "GET", "/api/flagged", nil); body != "flagged" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "GET", "/api/nope", nil); body != "root 404" {
+		t.Fatalf(body)
 	}
 }
 
-func TestNestedGroups(t *testing.T) {
-	handlerPrintCounter := func(w http.ResponseWriter, r *http.Request) {
-		counter, _ := r.Context().Value(ctxKey{"counter"}).(int)
-		w.Write([]byte(fmt.Sprintf("%v", counter)))
+func TestMuxMatchFunc(t *testing.T) {
+	version := func(v string) func(r *http.Request) bool {
+		return func(r *http.Request) bool { return r.Header.Get("X-API-Version") == v }
 	}
 
-	mwIncreaseCounter := func(next http.Handler) http.Handler {
+	r := NewRouter()
+	r.MatchFunc(version("2"), "/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("v2 " + URLParam(r, "id")))
+	})
+	r.MatchFunc(version("3"), "/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("v3 " + URLParam(r, "id")))
+	})
+	r.MatchFunc(func(r *http.Request) bool { return r.Header.Get("X-API-Version") != "" }, "/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("unknown"))
+	})
+	r.MethodFunc("GET", "/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("v1 " + URLParam(r, "id")))
+	})
+	r.With(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := r.Context()
-			counter, _ := ctx.Value(ctxKey{"counter"}).(int)
-			counter++
-			ctx = context.WithValue(ctx, ctxKey{"counter"}, counter)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			w.Header().Set("X-Beta", "1")
+			next.ServeHTTP(w, r)
 		})
-	}
-
-	// Each route represents value of its counter (number of applied middlewares).
-	r := New() // counter == 0
-	r.MethodFunc("GET", "/0", handlerPrintCounter)
-	r.Group(func(r Router) {
-		r.Use(mwIncreaseCounter) // counter == 1
-		r.MethodFunc("GET", "/1", handlerPrintCounter)
-
-		// r.Handle(GET, "/2", Chain(mwIncreaseCounter).HandlerFunc(handlerPrintCounter))
-		r.With(mwIncreaseCounter).MethodFunc("GET", "/2", handlerPrintCounter)
+	}).MatchFunc(version("beta"), "/beta", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("beta"))
+	})
 
-		r.Group(func(r Router) {
-			r.Use(mwIncreaseCounter, mwIncreaseCounter) // counter == 3
-			r.MethodFunc("GET", "/3", handlerPrintCounter)
-		})
-		r.Route("/", func(r Router) {
-			r.Use(mwIncreaseCounter, mwIncreaseCounter) // counter == 3
+	get := func(path, v string) (*http.Response, string) {
+		req, _ := http.NewRequest("GET", path, nil)
+		if v != "" {
+			req.Header.Set("X-API-Version", v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Result(), w.Body.String()
+	}
 
-			// r.Handle(GET, "/4", Chain(mwIncreaseCounter).HandlerFunc(handlerPrintCounter))
-			r.With(mwIncreaseCounter).MethodFunc("GET", "/4", handlerPrintCounter)
+	if _, body := get("/users/7", "2"); body != "v2 7" {
+		t.Fatalf(body)
+	}
+	if _, body := get("/users/7", "3"); body != "v3 7" {
+		t.Fatalf(body)
+	}
+	if _, body := get("/users/7", "1"); body != "unknown" {
+		t.Fatalf(body)
+	}
+	if _, body := get("/users/7", ""); body != "v1 7" {
+		t.Fatalf(body)
+	}
+	if resp, body := get("/beta", "beta"); body != "beta" || resp.Header.Get("X-Beta") != "1" {
+		t.Fatalf(body)
+	}
+	if resp, _ := get("/beta", ""); resp.StatusCode != 404 {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
 
-			r.Group(func(r Router) {
-				r.Use(mwIncreaseCounter, mwIncreaseCounter) // counter == 5
-				r.MethodFunc("GET", "/5", handlerPrintCounter)
-				// r.Handle(GET, "/6", Chain(mwIncreaseCounter).HandlerFunc(handlerPrintCounter))
-				r.With(mwIncreaseCounter).MethodFunc("GET", "/6", handlerPrintCounter)
+func TestMuxMatchFuncOrder(t *testing.T) {
+	beta := func(r *http.Request) bool { return r.Header.Get("X-Beta") == "1" }
+	get := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("get")) }
+	match := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("beta")) }
 
-			})
-		})
+	var registered []string
+	methodFirst := NewRouter()
+	methodFirst.OnRoute(func(method, pattern string, h http.Handler) {
+		registered = append(registered, method+" "+pattern)
 	})
+	methodFirst.MethodFunc("GET", "/x", get)
+	methodFirst.MatchFunc(beta, "/x", match)
 
-	ts := httptest.NewServer(r)
-	defer ts.Close()
+	matchFirst := NewRouter()
+	matchFirst.MatchFunc(beta, "/x", match)
+	matchFirst.MethodFunc("GET", "/x", get)
 
-	for _, route := range []string{"0", "1", "2", "3", "4", "5", "6"} {
-		if _, body := testRequest(t, ts, "GET", "/"+route, nil); body != route {
-			t.Errorf("expected %v, got %v", route, body)
+	if strings.Join(registered, ",") != "GET /x,* /x" {
+		t.Fatalf("unexpected registrations: %v", registered)
+	}
+
+	tests := []struct {
+		method string
+		beta   bool
+		status int
+		body   string
+		allow  string
+	}{
+		{"GET", false, 200, "get", ""},
+		{"GET", true, 200, "beta", ""},
+		{"POST", true, 200, "beta", ""},
+		{"POST", false, 405, "", "GET"},
+	}
+	for name, r := range map[string]*Mux{"method first": methodFirst, "match first": matchFirst} {
+		for _, tc := range tests {
+			req := httptest.NewRequest(tc.method, "/x", nil)
+			if tc.beta {
+				req.Header.Set("X-Beta", "1")
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.status || w.Body.String() != tc.body || w.Header().Get("Allow") != tc.allow {
+				t.Fatalf("%s: %s beta=%v: unexpected response %d %q, Allow %q", name, tc.method, tc.beta, w.Code, w.Body.String(), w.Header().Get("Allow"))
+			}
 		}
 	}
 }
 
-func TestMiddlewarePanicOnLateUse(t *testing.T) {
-	handler := func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("hello\n"))
-	}
+func TestMuxDoc(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
 
-	mw := func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			next.ServeHTTP(w, r)
-		})
+	r := NewRouter()
+	r.MethodFunc("GET", "/users", h)
+	r.MethodFunc("POST", "/users", h)
+	r.HandleFunc("/ping", h)
+	r.Doc("GET", "/users", RouteDoc{Summary: "List users", Tags: []string{"users"}})
+	r.Doc("*", "/ping", RouteDoc{Summary: "Health check"})
+
+	docs := map[string]map[string]*RouteDoc{}
+	for _, route := range r.Routes() {
+		docs[route.Pattern] = route.Docs
+	}
+	if d := docs["/users"]; len(d) != 1 || d["GET"].Summary != "List users" || d["GET"].Tags[0] != "users" {
+		t.Fatalf("unexpected /users docs: %v", d)
+	}
+	if d := docs["/ping"]; d["*"] == nil || d["*"].Summary != "Health check" || d["POST"].Summary != "Health check" {
+		t.Fatalf("unexpected /ping docs: %v", d)
 	}
 
 	defer func() {
 		if recover() == nil {
-			t.Error("expected panic()")
+			t.Fatal("expected a panic documenting a missing route")
 		}
 	}()
-
-	r := New()
-	r.MethodFunc("GET", "/", handler)
-	r.Use(mw) // Too late to apply middleware, we're expecting panic().
+	r.Doc("DELETE", "/users", RouteDoc{})
 }
 
-func TestMountingExistingPath(t *testing.T) {
-	handler := func(w http.ResponseWriter, r *http.Request) {}
+func TestRouteHandleDoc(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	r := NewRouter()
+	r.Register("GET,POST", "/users", h).Doc(RouteDoc{Summary: "Users"})
+	r.Register("DELETE", "/users", h)
+	r.Route("/admin", func(r Router) {
+		r.Register("*", "/ping", h).Doc(RouteDoc{Summary: "Health check"})
+	})
+
+	docs := map[string]map[string]*RouteDoc{}
+	for _, route := range r.Routes() {
+		docs[route.Pattern] = route.Docs
+		if route.SubRoutes != nil {
+			for _, sub := range route.SubRoutes.Routes() {
+				docs[route.Pattern+sub.Pattern] = sub.Docs
+			}
+		}
+	}
+	if d := docs["/users"]; len(d) != 2 || d["GET"].Summary != "Users" || d["POST"].Summary != "Users" {
+		t.Fatalf("unexpected /users docs: %v", d)
+	}
+	if d := docs["/admin/*/ping"]; d["*"] == nil || d["*"].Summary != "Health check" {
+		t.Fatalf("unexpected /admin/ping docs: %v", d)
+	}
+}
+
+func TestMuxRouteIfUseIf(t *testing.T) {
+	header := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(name, "1")
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }
+
+	r := NewRouter()
+	r.UseIf(true, header("X-On"))
+	r.UseIf(false, header("X-Off"))
+	if sr := r.RouteIf(true, "/beta", func(r Router) { r.MethodFunc("GET", "/", h) }); sr == nil {
+		t.Fatal("expected the subrouter to be returned")
+	}
+	if sr := r.RouteIf(false, "/legacy", func(r Router) { r.MethodFunc("GET", "/", h) }); sr != nil {
+		t.Fatal("expected no subrouter")
+	}
+
+	resp, body := testHandler(t, r, "GET", "/beta", nil)
+	if body != "ok" || resp.Header.Get("X-On") != "1" || resp.Header.Get("X-Off") != "" {
+		t.Fatalf("unexpected response: %q %v", body, resp.Header)
+	}
+	if resp, _ := testHandler(t, r, "GET", "/legacy", nil); resp.StatusCode != 404 {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestMuxFallback(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/legacy/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("upstream " + r.URL.Path))
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	r := NewRouter()
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte("not found"))
+	})
+	r.Fallback(func(ctx context.Context, r *http.Request) render.Render {
+		if !strings.HasPrefix(r.URL.Path, "/legacy/") {
+			return render.TextWith("text/plain", "no fallback")
+		}
+		return render.Proxy(r, target)
+	})
+	r.MethodFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("root"))
+	})
+	r.Route("/api", func(r Router) {
+		r.MethodFunc("GET", "/users", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("users"))
+		})
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	if _, body := testRequest(t, ts, "GET", "/", nil); body != "root" {
+		t.Fatalf(body)
+	}
+	if resp, body := testRequest(t, ts, "GET", "/legacy/orders", nil); resp.StatusCode != 200 || body != "upstream /legacy/orders" {
+		t.Fatalf("expected the proxied response, got %d %q", resp.StatusCode, body)
+	}
+	if resp, _ := testRequest(t, ts, "GET", "/legacy/missing", nil); resp.StatusCode != 404 {
+		t.Fatalf("expected the upstream 404, got %d", resp.StatusCode)
+	}
+	if _, body := testRequest(t, ts, "GET", "/other", nil); body != "no fallback" {
+		t.Fatalf(body)
+	}
+	if _, body := testRequest(t, ts, "GET", "/api/missing", nil); body != "no fallback" {
+		t.Fatalf("expected the subrouter to use the fallback, got %q", body)
+	}
+	if resp, _ := testRequest(t, ts, "POST", "/", nil); resp.StatusCode != 405 {
+		t.Fatalf("expected 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestMuxRegisterRoutes(t *testing.T) {
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(401)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	r := NewRouter()
+	r.RegisterRoutes([]RouteDef{
+		{Method: "GET", Pattern: "/users", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("list"))
+		})},
+		{Method: "GET", Pattern: "/users/{id}", Handler: HandlerFunc(func(ctx context.Context, r *http.Request) render.Render {
+			return render.Text("user " + URLParam(r, "id"))
+		})},
+		{Method: "POST,PUT", Pattern: "/users/{id}", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("saved"))
+		}), Middlewares: []func(http.Handler) http.Handler{auth}},
+		{Pattern: "/ping", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("pong"))
+		})},
+	})
+
+	if _, body := testHandler(t, r, "GET", "/users", nil); body != "list" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "GET", "/users/7", nil); body != "user 7" {
+		t.Fatalf(body)
+	}
+	if resp, _ := testHandler(t, r, "PUT", "/users/7", nil); resp.StatusCode != 401 {
+		t.Fatalf("expected the route middleware to apply, got %d", resp.StatusCode)
+	}
+	req := httptest.NewRequest("POST", "/users/7", nil)
+	req.Header.Set("Authorization", "token")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "saved" {
+		t.Fatalf(w.Body.String())
+	}
+	if _, body := testHandler(t, r, "DELETE", "/ping", nil); body != "pong" {
+		t.Fatalf(body)
+	}
+}
+
+func TestMuxMiddlewareOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, ">"+name)
+				next.ServeHTTP(w, r)
+				calls = append(calls, "<"+name)
+			})
+		}
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}
+
+	r := NewRouter()
+	r.Use(record("use1"), record("use2"))
+	r.Use(record("use3"))
+	r.With(record("with1"), record("with2")).With(record("with3")).MethodFunc("GET", "/with", handler)
+	r.Group(func(r Router) {
+		r.Use(record("group1"))
+		r.With(record("with1")).MethodFunc("GET", "/group", handler)
+	})
+	r.Route("/sub", func(r Router) {
+		r.Use(record("sub1"), record("sub2"))
+		r.With(record("with1")).MethodFunc("GET", "/", handler)
+	})
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/with", ">use1 >use2 >use3 >with1 >with2 >with3 handler <with3 <with2 <with1 <use3 <use2 <use1"},
+		{"/group", ">use1 >use2 >use3 >group1 >with1 handler <with1 <group1 <use3 <use2 <use1"},
+		{"/sub/", ">use1 >use2 >use3 >sub1 >sub2 >with1 handler <with1 <sub2 <sub1 <use3 <use2 <use1"},
+	}
+	for _, tc := range tests {
+		calls = nil
+		testHandler(t, r, "GET", tc.path, nil)
+		if got := strings.Join(calls, " "); got != tc.expected {
+			t.Fatalf("%s: expected\n%s\ngot\n%s", tc.path, tc.expected, got)
+		}
+	}
+}
+
+func TestRouteMiddlewares(t *testing.T) {
+	mw := func(next http.Handler) http.Handler { return next }
+	use1 := func(next http.Handler) http.Handler { return next }
+	sub1 := func(next http.Handler) http.Handler { return next }
+	group1 := func(next http.Handler) http.Handler { return next }
+	with1 := func(next http.Handler) http.Handler { return next }
+
+	var got Middlewares
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		got = RouteMiddlewares(r)
+	}
+
+	r := NewRouter()
+	r.Use(use1)
+	r.MethodFunc("GET", "/", handler)
+	r.Route("/sub", func(r Router) {
+		r.Use(sub1)
+		r.Group(func(r Router) {
+			r.Use(group1)
+			r.With(with1).MethodFunc("GET", "/group", handler)
+		})
+	})
+	r.With(mw).Route("/inline", func(r Router) {
+		r.MethodFunc("GET", "/", handler)
+	})
+
+	tests := []struct {
+		path     string
+		expected Middlewares
+	}{
+		{"/", Middlewares{use1}},
+		{"/sub/group", Middlewares{use1, sub1, group1, with1}},
+		{"/inline/", Middlewares{use1, mw}},
+	}
+	for _, tc := range tests {
+		got = nil
+		testHandler(t, r, "GET", tc.path, nil)
+		if len(got) != len(tc.expected) {
+			t.Fatalf("%s: expected %d middlewares, got %d", tc.path, len(tc.expected), len(got))
+		}
+		for i := range got {
+			if fmt.Sprintf("%p", got[i]) != fmt.Sprintf("%p", tc.expected[i]) {
+				t.Fatalf("%s: unexpected middleware at %d", tc.path, i)
+			}
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	if mws := RouteMiddlewares(req); mws != nil {
+		t.Fatalf("expected no middlewares outside of a router, got %d", len(mws))
+	}
+}
+
+func TestMuxStack(t *testing.T) {
+	tag := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(name + " "))
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}
+
+	r := NewRouter()
+	r.UseStack("authed", tag("auth"), tag("ratelimit"))
+	r.UseStack("admin", tag("admin"))
+	r.MethodFunc("GET", "/public", handler)
+	r.Stack("authed").MethodFunc("GET", "/account", handler)
+	r.Stack("authed", "admin").MethodFunc("GET", "/admin", handler)
+	r.Route("/api", func(r Router) {
+		r.Stack("authed").With(tag("with")).MethodFunc("GET", "/me", handler)
+	})
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/public", "ok"},
+		{"/account", "auth ratelimit ok"},
+		{"/admin", "auth ratelimit admin ok"},
+		{"/api/me", "auth ratelimit with ok"},
+	}
+	for _, tc := range tests {
+		if _, body := testHandler(t, r, "GET", tc.path, nil); body != tc.body {
+			t.Fatalf("%s: expected %q, got %q", tc.path, tc.body, body)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic using an undefined stack")
+		}
+	}()
+	r.Stack("missing")
+}
+
+func TestRouteHandleStack(t *testing.T) {
+	tag := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(name + " "))
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	r := NewRouter()
+	r.UseStack("authed", tag("auth"), tag("ratelimit"))
+	r.UseStack("admin", tag("admin"))
+	r.Register("GET", "/public", handler)
+	r.Register("GET", "/account", handler).Stack("authed")
+	r.With(tag("with")).Register("*", "/admin", handler).Stack("authed", "admin")
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/public", "ok"},
+		{"/account", "auth ratelimit ok"},
+		{"/admin", "auth ratelimit admin with ok"},
+	}
+	for _, tc := range tests {
+		if _, body := testHandler(t, r, "GET", tc.path, nil); body != tc.body {
+			t.Fatalf("%s: expected %q, got %q", tc.path, tc.body, body)
+		}
+	}
+
+	for _, route := range r.Routes() {
+		if route.Pattern != "/admin" {
+			continue
+		}
+		if ch, ok := route.Handlers["GET"].(*ChainHandler); !ok || len(ch.Middlewares) != 4 {
+			t.Fatalf("expected the route to report its 4 middlewares, got %#v", route.Handlers["GET"])
+		}
+	}
+}
+
+func TestMuxAccepts(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}
+
+	r := NewRouter()
+	r.MethodFunc("POST", "/users", h)
+	r.MethodFunc("PUT", "/users", h)
+	r.MethodFunc("POST", "/uploads", h)
+	r.Accepts("POST", "/users", "application/json")
+
+	post := func(path, ctype string) (int, string) {
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"name":"x"}`))
+		if ctype != "" {
+			req.Header.Set("Content-Type", ctype)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	if code, body := post("/users", "application/json; charset=utf-8"); code != 200 || body != "ok" {
+		t.Fatalf("expected 200, got %d %q", code, body)
+	}
+	if code, _ := post("/users", "text/plain"); code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d", code)
+	}
+	if code, _ := post("/users", ""); code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d", code)
+	}
+
+	// other routes and methods are unaffected
+	if code, _ := post("/uploads", "text/plain"); code != 200 {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	req := httptest.NewRequest("PUT", "/users", strings.NewReader("x"))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic restricting a missing route")
+		}
+	}()
+	r.Accepts("DELETE", "/users", "application/json")
+}
+
+func TestRouteHandleAccepts(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	r := NewRouter()
+	r.Register("POST,PUT", "/users", h).Accepts("application/json")
+	r.Register("POST", "/uploads", h)
+
+	send := func(method, path, ctype string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"name":"x"}`))
+		req.Header.Set("Content-Type", ctype)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send("POST", "/users", "application/json"); code != 200 {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := send("PUT", "/users", "text/plain"); code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d", code)
+	}
+	if code := send("POST", "/uploads", "text/plain"); code != 200 {
+		t.Fatalf("expected the other route to be unaffected, got %d", code)
+	}
+}
+
+func TestMuxUseRawPath(t *testing.T) {
+	newRouter := func() *Mux {
+		r := NewRouter()
+		r.MethodFunc("GET", "/files/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("file " + URLParam(r, "id")))
+		})
+		r.MethodFunc("GET", "/files/{dir}/{name}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("dir " + URLParam(r, "dir") + " " + URLParam(r, "name")))
+		})
+		return r
+	}
+
+	// raw path, the default
+	r := newRouter()
+	if _, body := testHandler(t, r, "GET", "/files/a%2Fb", nil); body != "file a%2Fb" {
+		t.Fatalf(body)
+	}
+	// the path has no raw form when it's encoded the default way
+	if _, body := testHandler(t, r, "GET", "/files/a%20b", nil); body != "file a b" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "GET", "/files/a/b", nil); body != "dir a b" {
+		t.Fatalf(body)
+	}
+
+	// decoded path
+	r = newRouter()
+	r.UseRawPath(false)
+	if _, body := testHandler(t, r, "GET", "/files/a%2Fb", nil); body != "dir a b" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "GET", "/files/a%20b", nil); body != "file a b" {
+		t.Fatalf(body)
+	}
+}
+
+func TestMuxColonSyntax(t *testing.T) {
+	r := NewRouter()
+	r.ColonSyntax(true)
+	r.MethodFunc("GET", "/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("user " + URLParam(r, "id")))
+	})
+	r.MethodFunc("GET", "/articles/{slug:[a-z-]+}/:op", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(URLParam(r, "slug") + " " + URLParam(r, "op")))
+	})
+	r.Route("/orgs/:org", func(r Router) {
+		r.MethodFunc("GET", "/repos/:repo/*", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(URLParam(r, "org") + "/" + URLParam(r, "repo") + " " + URLParam(r, "*")))
+		})
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	if _, body := testRequest(t, ts, "GET", "/users/7", nil); body != "user 7" {
+		t.Fatalf(body)
+	}
+	if _, body := testRequest(t, ts, "GET", "/articles/hello-world/edit", nil); body != "hello-world edit" {
+		t.Fatalf(body)
+	}
+	if _, body := testRequest(t, ts, "GET", "/orgs/goclover/repos/clover/tree/main", nil); body != "goclover/clover tree/main" {
+		t.Fatalf(body)
+	}
+
+	var patterns []string
+	Walk(r, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		patterns = append(patterns, route)
+		return nil
+	})
+	sort.Strings(patterns)
+	if got := strings.Join(patterns, " "); got != "/articles/{slug:[a-z-]+}/{op} /orgs/{org}/repos/{repo}/* /users/{id}" {
+		t.Fatalf("unexpected patterns: %s", got)
+	}
+
+	// the brace syntax is the default
+	r2 := NewRouter()
+	r2.MethodFunc("GET", "/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("literal"))
+	})
+	if _, body := testHandler(t, r2, "GET", "/users/:id", nil); body != "literal" {
+		t.Fatalf(body)
+	}
+	if resp, _ := testHandler(t, r2, "GET", "/users/7", nil); resp.StatusCode != 404 {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestMuxAlias(t *testing.T) {
+	var calls int
+	mw := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	r := NewRouter()
+	r.With(mw).MethodFunc("GET", "/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok " + RouteContext(r.Context()).RoutePattern()))
+	})
+	r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("any " + URLParam(r, "id")))
+	})
+	r.MethodFunc("POST", "/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("post " + URLParam(r, "id")))
+	})
+	r.Alias("/health", "/healthz", "/status")
+	r.Alias("/users/{id}", "/members/{id}")
+
+	for _, path := range []string{"/health", "/healthz", "/status"} {
+		if _, body := testHandler(t, r, "GET", path, nil); body != "ok "+path {
+			t.Fatalf(body)
+		}
+	}
+	if calls != 3 {
+		t.Fatalf("expected the middleware chain to be shared, got %d calls", calls)
+	}
+	if resp, _ := testHandler(t, r, "POST", "/healthz", nil); resp.StatusCode != 405 {
+		t.Fatalf("expected 405, got %d", resp.StatusCode)
+	}
+	if _, body := testHandler(t, r, "GET", "/members/7", nil); body != "any 7" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "POST", "/members/7", nil); body != "post 7" {
+		t.Fatalf(body)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic aliasing a missing route")
+		}
+	}()
+	r.Alias("/missing", "/gone")
+}
+
+func TestSingleHandler(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := URLParam(r, "name")
+		w.Write([]byte("hi " + name))
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	rctx := NewRouteContext()
+	r = r.WithContext(context.WithValue(r.Context(), RouteCtxKey, rctx))
+	rctx.URLParams.Add("name", "joe")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	body := w.Body.String()
+	expected := "hi joe"
+	if body != expected {
+		t.Fatalf("expected:%s got:%s", expected, body)
+	}
+}
+
+// TODO: a Router wrapper test..
+//
+// type ACLMux struct {
+// 	*Mux
+// 	XX string
+// }
+//
+// func NewACLMux() *ACLMux {
+// 	return &ACLMux{Mux: New(), XX: "hihi"}
+// }
+//
+// // TODO: this should be supported...
+// func TestWoot(t *testing.T) {
+// 	var r Router = New()
+//
+// 	var r2 Router = NewACLMux() //New()
+// 	r2.MethodFunc("GET","/hi", func(w http.ResponseWriter, r *http.Request) {
+// 		w.Write([]byte("hi"))
+// 	})
+//
+// 	r.Mount("/", r2)
+// }
+
+func TestServeHTTPExistingContext(t *testing.T) {
+	r := New()
+	r.MethodFunc("GET", "/hi", func(w http.ResponseWriter, r *http.Request) {
+		s, _ := r.Context().Value(ctxKey{"testCtx"}).(string)
+		w.Write([]byte(s))
+	})
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := r.Context().Value(ctxKey{"testCtx"}).(string)
+		w.WriteHeader(404)
+		w.Write([]byte(s))
+	})
+
+	testcases := []struct {
+		Ctx            context.Context
+		Method         string
+		Path           string
+		ExpectedBody   string
+		ExpectedStatus int
+	}{
+		{
+			Method:         "GET",
+			Path:           "/hi",
+			Ctx:            context.WithValue(context.Background(), ctxKey{"testCtx"}, "hi ctx"),
+			ExpectedStatus: 200,
+			ExpectedBody:   "hi ctx",
+		},
+		{
+			Method:         "GET",
+			Path:           "/hello",
+			Ctx:            context.WithValue(context.Background(), ctxKey{"testCtx"}, "nothing here ctx"),
+			ExpectedStatus: 404,
+			ExpectedBody:   "nothing here ctx",
+		},
+	}
+
+	for _, tc := range testcases {
+		resp := httptest.NewRecorder()
+		req, err := http.NewRequest(tc.Method, tc.Path, nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		req = req.WithContext(tc.Ctx)
+		r.ServeHTTP(resp, req)
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if resp.Code != tc.ExpectedStatus {
+			t.Fatalf("%v != %v", tc.ExpectedStatus, resp.Code)
+		}
+		if string(b) != tc.ExpectedBody {
+			t.Fatalf("%s != %s", tc.ExpectedBody, b)
+		}
+	}
+}
+
+func TestNestedGroups(t *testing.T) {
+	handlerPrintCounter := func(w http.ResponseWriter, r *http.Request) {
+		counter, _ := r.Context().Value(ctxKey{"counter"}).(int)
+		w.Write([]byte(fmt.Sprintf("%v", counter)))
+	}
+
+	mwIncreaseCounter := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			counter, _ := ctx.Value(ctxKey{"counter"}).(int)
+			counter++
+			ctx = context.WithValue(ctx, ctxKey{"counter"}, counter)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+
+	// Each route represents value of its counter (number of applied middlewares).
+	r := New() // counter == 0
+	r.MethodFunc("GET", "/0", handlerPrintCounter)
+	r.Group(func(r Router) {
+		r.Use(mwIncreaseCounter) // counter == 1
+		r.MethodFunc("GET", "/1", handlerPrintCounter)
+
+		// r.Handle(GET, "/2", Chain(mwIncreaseCounter).HandlerFunc(handlerPrintCounter))
+		r.With(mwIncreaseCounter).MethodFunc("GET", "/2", handlerPrintCounter)
+
+		r.Group(func(r Router) {
+			r.Use(mwIncreaseCounter, mwIncreaseCounter) // counter == 3
+			r.MethodFunc("GET", "/3", handlerPrintCounter)
+		})
+		r.Route("/", func(r Router) {
+			r.Use(mwIncreaseCounter, mwIncreaseCounter) // counter == 3
+
+			// r.Handle(GET, "/4", Chain(mwIncreaseCounter).HandlerFunc(handlerPrintCounter))
+			r.With(mwIncreaseCounter).MethodFunc("GET", "/4", handlerPrintCounter)
+
+			r.Group(func(r Router) {
+				r.Use(mwIncreaseCounter, mwIncreaseCounter) // counter == 5
+				r.MethodFunc("GET", "/5", handlerPrintCounter)
+				// r.Handle(GET, "/6", Chain(mwIncreaseCounter).HandlerFunc(handlerPrintCounter))
+				r.With(mwIncreaseCounter).MethodFunc("GET", "/6", handlerPrintCounter)
+
+			})
+		})
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	for _, route := range []string{"0", "1", "2", "3", "4", "5", "6"} {
+		if _, body := testRequest(t, ts, "GET", "/"+route, nil); body != route {
+			t.Errorf("expected %v, got %v", route, body)
+		}
+	}
+}
+
+func TestMiddlewarePanicOnLateUse(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello\n"))
+	}
+
+	mw := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic()")
+		}
+	}()
+
+	r := New()
+	r.MethodFunc("GET", "/", handler)
+	r.Use(mw) // Too late to apply middleware, we're expecting panic().
+}
+
+func TestMountingExistingPath(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
 
 	defer func() {
 		if recover() == nil {
@@ -1354,6 +2240,48 @@ func TestMountingExistingPath(t *testing.T) {
 	r.Mount("/hi", http.HandlerFunc(handler))
 }
 
+func TestMuxRouteConflicts(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	expectPanic := func(msg string, fn func()) {
+		t.Helper()
+		defer func() {
+			rcv := recover()
+			if rcv == nil {
+				t.Fatalf("expected panic %q", msg)
+			}
+			if got := fmt.Sprint(rcv); got != msg {
+				t.Fatalf("expected panic %q, got %q", msg, got)
+			}
+		}()
+		fn()
+	}
+
+	expectPanic("clover: route 'GET /users/{id}' is already registered", func() {
+		r := New()
+		r.MethodFunc("GET", "/users/{id}", handler)
+		r.MethodFunc("GET", "/users/{id}", handler)
+	})
+
+	expectPanic("clover: routing pattern '/users/{b}' conflicts with '/users/{a}', their params are named differently at the same position", func() {
+		r := New()
+		r.MethodFunc("GET", "/users/{a}", handler)
+		r.MethodFunc("GET", "/users/{b}", handler)
+	})
+
+	expectPanic("clover: route 'GET /x' is already registered", func() {
+		r := New()
+		r.MethodFunc("GET", "/x", handler)
+		r.HandleFunc("/x", handler)
+	})
+
+	// distinct methods may still name their params differently
+	r := New()
+	r.MethodFunc("GET", "/users/{id}", handler)
+	r.MethodFunc("DELETE", "/users/{slug}", handler)
+	r.MethodFunc("POST", "/users/{id}", handler)
+}
+
 func TestMountingSimilarPattern(t *testing.T) {
 	r := New()
 	r.MethodFunc("GET", "/hi", func(w http.ResponseWriter, r *http.Request) {
@@ -1436,6 +2364,28 @@ func TestMuxWildcardRoute(t *testing.T) {
 	r.MethodFunc("GET", "/*/wildcard/must/be/at/end", handler)
 }
 
+func TestMuxWildcardParam(t *testing.T) {
+	r := New()
+	r.MethodFunc("GET", "/assets/*", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(URLParam(r, "*")))
+	})
+	r.Route("/static", func(r Router) {
+		r.MethodFunc("GET", "/*", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(URLParam(r, "*")))
+		})
+	})
+
+	if _, body := testHandler(t, r, "GET", "/assets/css/app.css", nil); body != "css/app.css" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "GET", "/assets/", nil); body != "" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "GET", "/static/js/app.js", nil); body != "js/app.js" {
+		t.Fatalf(body)
+	}
+}
+
 func TestMuxWildcardRouteCheckTwo(t *testing.T) {
 	handler := func(w http.ResponseWriter, r *http.Request) {}
 
@@ -1522,6 +2472,58 @@ func TestMuxRegexp3(t *testing.T) {
 	}
 }
 
+func TestMuxFileExtensions(t *testing.T) {
+	r := NewRouter()
+	r.MethodFunc("GET", "/sitemap.{ext:xml|txt}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(URLParam(r, "ext")))
+	})
+
+	if _, body := testHandler(t, r, "GET", "/sitemap.xml", nil); body != "xml" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "GET", "/sitemap.txt", nil); body != "txt" {
+		t.Fatalf(body)
+	}
+	for _, path := range []string{"/sitemap.json", "/sitemap.xmlx", "/sitemap.atxt"} {
+		if resp, _ := testHandler(t, r, "GET", path, nil); resp.StatusCode != 404 {
+			t.Fatalf("%s: expected 404, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestMuxRegexpAnonymous(t *testing.T) {
+	r := NewRouter()
+	r.MethodFunc("GET", "/date/{:\\d\\d\\d\\d}/{:\\d\\d}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(URLParam(r, "0") + "-" + URLParam(r, "1")))
+	})
+	r.MethodFunc("GET", "/page/{name}/{:\\d+}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(URLParam(r, "name") + "-" + URLParam(r, "0")))
+	})
+
+	if _, body := testHandler(t, r, "GET", "/date/2017/04", nil); body != "2017-04" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "GET", "/page/intro/3", nil); body != "intro-3" {
+		t.Fatalf(body)
+	}
+	if resp, _ := testHandler(t, r, "GET", "/date/17/04", nil); resp.StatusCode != 404 {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestMuxRegexpInvalid(t *testing.T) {
+	defer func() {
+		rcv := recover()
+		msg, _ := rcv.(string)
+		if !strings.Contains(msg, "/users/{id:[}") || !strings.Contains(msg, "missing closing ]") {
+			t.Fatalf("unexpected panic: %v", rcv)
+		}
+	}()
+
+	r := NewRouter()
+	r.MethodFunc("GET", "/users/{id:[}", func(w http.ResponseWriter, r *http.Request) {})
+}
+
 func TestMuxSubrouterWildcardParam(t *testing.T) {
 	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "param:%v *:%v", URLParam(r, "param"), URLParam(r, "*"))
@@ -1722,6 +2724,163 @@ func TestServerBaseContext(t *testing.T) {
 	}
 }
 
+func TestMuxHandlerContextCanceled(t *testing.T) {
+	started := make(chan struct{})
+	done := make(chan error, 1)
+
+	r := New()
+	r.Handle("/", func(ctx context.Context, r *http.Request) render.Render {
+		if ctx != r.Context() {
+			done <- fmt.Errorf("handler context is not the request context")
+			return render.Text("")
+		}
+		close(started)
+		select {
+		case <-ctx.Done():
+			done <- nil
+		case <-time.After(2 * time.Second):
+			done <- fmt.Errorf("handler context was not canceled")
+		}
+		return render.Text("")
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/", nil)
+	go func() {
+		<-started
+		cancel()
+	}()
+	http.DefaultClient.Do(req)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMuxRequestTimeout(t *testing.T) {
+	r := New()
+	r.RequestTimeout(50 * time.Millisecond)
+	r.Handle("/", func(ctx context.Context, r *http.Request) render.Render {
+		if _, ok := ctx.Deadline(); !ok {
+			return render.Text("no deadline")
+		}
+		select {
+		case <-ctx.Done():
+			return render.Text(ctx.Err().Error())
+		case <-time.After(time.Second):
+			return render.Text("not canceled")
+		}
+	})
+
+	if _, body := testHandler(t, r, "GET", "/", nil); body != context.DeadlineExceeded.Error() {
+		t.Fatalf(body)
+	}
+}
+
+func TestMuxStaticFastPath(t *testing.T) {
+	r := New()
+	r.MethodFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("root"))
+	})
+	r.MethodFunc("GET", "/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong " + RouteContext(r.Context()).RoutePattern()))
+	})
+	r.With(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-With", "1")
+			next.ServeHTTP(w, r)
+		})
+	}).MethodFunc("POST", "/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("posted"))
+	})
+
+	if r.static.dynamic {
+		t.Fatal("expected the static index to be in use")
+	}
+	if _, body := testHandler(t, r, "GET", "/", nil); body != "root" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "GET", "/ping", nil); body != "pong /ping" {
+		t.Fatalf(body)
+	}
+	if resp, body := testHandler(t, r, "POST", "/ping", nil); body != "posted" || resp.Header.Get("X-With") != "1" {
+		t.Fatalf(body)
+	}
+	if resp, _ := testHandler(t, r, "PUT", "/ping", nil); resp.StatusCode != 405 {
+		t.Fatalf("expected 405, got %d", resp.StatusCode)
+	}
+	if resp, _ := testHandler(t, r, "GET", "/ping/", nil); resp.StatusCode != 404 {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+
+	r.Group(func(r Router) {
+		r.MethodFunc("GET", "/ping/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("pong " + URLParam(r, "id")))
+		})
+	})
+
+	if !r.static.dynamic {
+		t.Fatal("expected the router to fall back to the tree")
+	}
+	if _, body := testHandler(t, r, "GET", "/ping", nil); body != "pong /ping" {
+		t.Fatalf(body)
+	}
+	if _, body := testHandler(t, r, "GET", "/ping/1", nil); body != "pong 1" {
+		t.Fatalf(body)
+	}
+}
+
+func TestMuxOnRoute(t *testing.T) {
+	var registered []string
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := NewRouter()
+	r.MethodFunc("GET", "/before", h)
+	r.OnRoute(func(method, pattern string, h http.Handler) {
+		if h == nil {
+			t.Fatalf("expected a handler for %s %s", method, pattern)
+		}
+		registered = append(registered, method+" "+pattern)
+	})
+
+	r.MethodFunc("GET,POST", "/users", h)
+	r.HandleFunc("/any", h)
+	r.With(func(next http.Handler) http.Handler { return next }).MethodFunc("DELETE", "/users/{id}", h)
+	r.Group(func(r Router) {
+		r.MethodFunc("PUT", "/users/{id}", h)
+	})
+	admin := r.Route("/admin", func(r Router) {
+		r.MethodFunc("GET", "/stats", h)
+	})
+	admin.MethodFunc("GET", "/audit", h)
+	api := r.PathPrefix("/api/")
+	api.MethodFunc("GET", "/users", h)
+	api.PathPrefix("/v2").MethodFunc("POST", "/users", h)
+
+	expected := []string{
+		"GET /users",
+		"POST /users",
+		"* /any",
+		"DELETE /users/{id}",
+		"PUT /users/{id}",
+		"GET /admin/stats",
+		"GET /admin/audit",
+		"GET /api/users",
+		"POST /api/v2/users",
+	}
+	if len(registered) != len(expected) {
+		t.Fatalf("expected %d registrations, got %d: %v", len(expected), len(registered), registered)
+	}
+	for i := range expected {
+		if registered[i] != expected[i] {
+			t.Fatalf("expected %q, got %q", expected[i], registered[i])
+		}
+	}
+}
+
 func testRequest(t *testing.T, ts *httptest.Server, method, path string, body io.Reader) (*http.Response, string) {
 	req, err := http.NewRequest(method, ts.URL+path, body)
 	if err != nil {
@@ -1809,3 +2968,31 @@ func BenchmarkMux(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkMuxStatic(b *testing.B) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	paths := []string{"/", "/healthz", "/api/users", "/api/users/search", "/api/orders", "/api/orders/export"}
+
+	static := New()
+	tree := New()
+	for _, path := range paths {
+		static.MethodFunc("GET", path, h)
+		tree.MethodFunc("GET", path, h)
+	}
+	// a single dynamic route makes the mux walk the routing tree
+	tree.MethodFunc("GET", "/api/{resource}/{id}", h)
+
+	for name, mx := range map[string]*Clover{"map": static, "tree": tree} {
+		b.Run(name, func(b *testing.B) {
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("GET", "/api/orders/export", nil)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				mx.ServeHTTP(w, r)
+			}
+		})
+	}
+}
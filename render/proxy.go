@@ -0,0 +1,30 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// Proxy forwards the request r to the target upstream and streams back its
+// response, turning a handler into a lightweight gateway. The request is
+// carried into the render, as WriteTo only has the http.ResponseWriter.
+//
+// Upstream failures are answered with a 502 Bad Gateway by the underlying
+// httputil.ReverseProxy, which can be customized through ProxyRender.Proxy.
+var Proxy = func(r *http.Request, target *url.URL) *ProxyRender {
+	return &ProxyRender{
+		Request: r,
+		Proxy:   httputil.NewSingleHostReverseProxy(target),
+	}
+}
+
+type ProxyRender struct {
+	Request *http.Request
+	Proxy   *httputil.ReverseProxy
+}
+
+func (p *ProxyRender) WriteTo(w http.ResponseWriter) error {
+	p.Proxy.ServeHTTP(w, p.Request)
+	return nil
+}
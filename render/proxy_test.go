@@ -0,0 +1,43 @@
+package render
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend", "1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(r.Method + " " + r.URL.RequestURI()))
+	}))
+	defer backend.Close()
+
+	target, _ := url.Parse(backend.URL)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := Proxy(r, target).WriteTo(w); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer gateway.Close()
+
+	resp, err := http.Post(gateway.URL+"/users?page=2", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("X-Backend") != "1" {
+		t.Fatal("expected backend headers to be proxied")
+	}
+	if string(body) != "POST /users?page=2" {
+		t.Fatalf("unexpected body: %q", body)
+	}
+}
@@ -2,7 +2,9 @@ package render
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 )
@@ -16,6 +18,12 @@ const (
 	HeaderLocation = "Location"
 )
 
+// FlushThreshold enables the incremental write of large bodies. When positive,
+// JSON and Text renders with a body larger than FlushThreshold bytes write it
+// in chunks of FlushThreshold bytes, flushing each to the client if the
+// http.ResponseWriter is a http.Flusher, which improves the time to first byte.
+var FlushThreshold = 0
+
 var JSON = func(data interface{}) *JSONRender {
 	bf, _ := json.Marshal(data)
 	return &JSONRender{
@@ -23,7 +31,84 @@ var JSON = func(data interface{}) *JSONRender {
 			Status: http.StatusOK,
 			Headers: http.Header{
 				HeaderContentTyp: []string{"application/json; charset=utf-8"},
-				HeaderContentLen: []string{strconv.Itoa(len(bf))},
+			},
+		},
+		Data: bf,
+	}
+}
+
+// Problem describes an API error as per RFC 7807, see ProblemJSON.
+type Problem struct {
+	// Type is a URI reference identifying the problem type, "about:blank"
+	// if empty.
+	Type string `json:"type"`
+
+	// Title is a short summary of the problem type, the status text if
+	// empty.
+	Title string `json:"title"`
+
+	// Status is the HTTP status code, set by ProblemJSON.
+	Status int `json:"status"`
+
+	// Detail explains this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+
+	// Instance is a URI reference identifying this occurrence of the
+	// problem.
+	Instance string `json:"instance,omitempty"`
+
+	// Extensions are additional members of the problem object, ie. a list
+	// of invalid params. They can't override the members above.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON flattens the Extensions into the problem object.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	if len(p.Extensions) == 0 {
+		return json.Marshal(problem(p))
+	}
+
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	bf, err := json.Marshal(problem(p))
+	if err != nil {
+		return nil, err
+	}
+	var std map[string]interface{}
+	if err := json.Unmarshal(bf, &std); err != nil {
+		return nil, err
+	}
+	for k, v := range std {
+		members[k] = v
+	}
+	return json.Marshal(members)
+}
+
+// ProblemJSON renders p as an `application/problem+json` response with the
+// given status, as per RFC 7807, which API gateways and clients understand
+// as a standard error format. The title defaults to the status text.
+//
+//	return render.ProblemJSON(http.StatusNotFound, render.Problem{
+//		Detail:   "user 42 doesn't exist",
+//		Instance: r.URL.Path,
+//	})
+var ProblemJSON = func(status int, p Problem) *JSONRender {
+	p.Status = status
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(status)
+	}
+	bf, _ := json.Marshal(p)
+	return &JSONRender{
+		NopRender: NopRender{
+			Status: status,
+			Headers: http.Header{
+				HeaderContentTyp: []string{"application/problem+json"},
 			},
 		},
 		Data: bf,
@@ -31,37 +116,204 @@ var JSON = func(data interface{}) *JSONRender {
 }
 
 var Text = func(text string) *TextRender {
+	return TextWith("text/plain; charset=utf-8", text)
+}
+
+// TextWith renders text with the given content type, ie. text/html or text/csv.
+var TextWith = func(contentType string, text string) *TextRender {
 	bf := []byte(text)
 	return &TextRender{
 		NopRender: NopRender{
 			Status: http.StatusOK,
 			Headers: http.Header{
-				HeaderContentTyp: []string{"text/plain; charset=utf-8"},
-				HeaderContentLen: []string{strconv.Itoa(len(bf))},
+				HeaderContentTyp: []string{contentType},
 			},
 		},
 		Text: bf,
 	}
 }
 
+// RedirectText is the plain text body of the redirects rendered without a
+// text, none by default as browsers ignore it.
+var RedirectText = ""
+
+// Redirect redirects to location with the given status. The text, if any, is
+// sent as a plain text body, and RedirectText otherwise, the response only
+// carrying the Location header when both are empty.
 var Redirect = func(status int, location string, text ...string) *RedirectRender {
-	bf := []byte("redirect")
+	body := RedirectText
 	if len(text) > 0 {
-		bf = []byte(strings.Join(text, ""))
+		body = strings.Join(text, "")
+	}
+	headers := http.Header{
+		HeaderLocation: []string{location},
+	}
+	if body != "" {
+		headers.Set(HeaderContentTyp, "text/plain; charset=utf-8")
 	}
 	return &RedirectRender{
+		NopRender: NopRender{
+			Status:  status,
+			Headers: headers,
+		},
+		Text: []byte(body),
+	}
+}
+
+// RedirectTemporary redirects to location with a 302 Found.
+var RedirectTemporary = func(location string) *RedirectRender {
+	return Redirect(http.StatusFound, location)
+}
+
+// RedirectPermanent redirects to location with a 301 Moved Permanently.
+var RedirectPermanent = func(location string) *RedirectRender {
+	return Redirect(http.StatusMovedPermanently, location)
+}
+
+// RedirectSeeOther redirects to location with a 303 See Other, typically
+// after handling a POST.
+var RedirectSeeOther = func(location string) *RedirectRender {
+	return Redirect(http.StatusSeeOther, location)
+}
+
+// JSONStream encodes the values received from ch as a JSON array, writing and
+// flushing each element as it arrives rather than buffering the whole
+// collection like JSON, until ch is closed. A closed channel without values
+// renders an empty array. When a value fails to encode, the stream is cut
+// short, leaving the array unterminated so clients don't mistake it for the
+// full collection, and ch is drained in the background so the producer isn't
+// blocked forever.
+var JSONStream = func(ch <-chan interface{}) *JSONStreamRender {
+	return &JSONStreamRender{
+		NopRender: NopRender{
+			Status: http.StatusOK,
+			Headers: http.Header{
+				HeaderContentTyp: []string{"application/json; charset=utf-8"},
+			},
+		},
+		Ch: ch,
+	}
+}
+
+// Reader streams the content of r to the client. The Content-Length is set
+// when length is known (>= 0), otherwise the response is chunked. If r is an
+// io.ReadCloser, it's closed once written.
+var Reader = func(status int, contentType string, length int64, r io.Reader) *ReaderRender {
+	return &ReaderRender{
 		NopRender: NopRender{
 			Status: status,
 			Headers: http.Header{
-				HeaderContentTyp: []string{"text/plain; charset=utf-8"},
-				HeaderContentLen: []string{strconv.Itoa(len(bf))},
-				HeaderLocation:   []string{location},
+				HeaderContentTyp: []string{contentType},
 			},
 		},
-		Text: bf,
+		Length: length,
+		Reader: r,
+	}
+}
+
+// Attachment renders data with a Content-Disposition header, so browsers
+// display it when disposition is "inline", ie. a PDF preview, and download it
+// as filename when it's "attachment", from the same code path. Any other
+// disposition is sent as "attachment". UTF-8 filenames are encoded as per
+// RFC 5987, with an ASCII fallback.
+var Attachment = func(disposition string, filename string, contentType string, data []byte) *AttachmentRender {
+	if !strings.EqualFold(disposition, "inline") {
+		disposition = "attachment"
+	}
+	return &AttachmentRender{
+		NopRender: NopRender{
+			Status: http.StatusOK,
+			Headers: http.Header{
+				HeaderContentTyp:      []string{contentType},
+				"Content-Disposition": []string{contentDisposition(strings.ToLower(disposition), filename)},
+			},
+		},
+		Data: data,
+	}
+}
+
+// NotModified answers a conditional request with a 304 Not Modified, with no
+// body. Content-Type and Content-Length, which are invalid on a 304, are
+// stripped even when set by a middleware.
+var NotModified = func() *NotModifiedRender {
+	return &NotModifiedRender{
+		NopRender: NopRender{
+			Status:  http.StatusNotModified,
+			Headers: http.Header{},
+		},
+	}
+}
+
+// EarlyHints sends a 103 Early Hints informational response with a Link
+// preload header for each of the links, so browsers preload assets while the
+// final response is being prepared. Unlike other renders it's written before
+// the final one, ie. from a http.Handler:
+//
+//	render.EarlyHints("/app.css", "/app.js").WriteTo(w)
+//	// ... render the page
+//
+// Links already in the Link header format, such as
+// `</font.woff2>; rel=preload; as=font; crossorigin`, are sent as is.
+var EarlyHints = func(links ...string) *EarlyHintsRender {
+	return &EarlyHintsRender{Links: links}
+}
+
+// SafeRedirectFallback is the location used by SafeRedirect when the
+// requested location points to a host that isn't allowed.
+var SafeRedirectFallback = "/"
+
+// SafeRedirect redirects to location with a 302 Found, guarding against open
+// redirects. Paths starting with a single "/" are always allowed, while other
+// locations (including scheme-relative "//host" forms) must point to one of
+// allowedHosts over http or https, otherwise SafeRedirectFallback is used
+// instead. Locations with surrounding spaces or control characters, which
+// browsers strip, are never allowed.
+var SafeRedirect = func(allowedHosts []string, location string) *RedirectRender {
+	if !isSafeRedirect(allowedHosts, location) {
+		location = SafeRedirectFallback
+	}
+	return RedirectTemporary(location)
+}
+
+func isSafeRedirect(allowedHosts []string, location string) bool {
+	if location == "" || strings.TrimSpace(location) != location {
+		return false
+	}
+	for i := 0; i < len(location); i++ {
+		if c := location[i]; c < ' ' || c == 0x7f {
+			return false
+		}
+	}
+
+	// browsers treat backslashes as slashes, so "/\\evil.com" is scheme-relative
+	location = strings.ReplaceAll(location, "\\", "/")
+	if location[0] == '/' {
+		return len(location) == 1 || location[1] != '/'
 	}
+
+	u, err := url.Parse(location)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := u.Hostname()
+	for _, h := range allowedHosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
 }
 
+// Render writes a response to the client.
+//
+// Renders with a body of known size set the Content-Length header in WriteTo,
+// right before the status code is written, rather than up front when they're
+// constructed. Middlewares that re-encode the body, such as middleware.Compress,
+// wrap the http.ResponseWriter and drop the header in their WriteHeader, so a
+// stale Content-Length never reaches the client.
 type Render interface {
 	WriteTo(w http.ResponseWriter) error
 }
@@ -72,14 +324,40 @@ type NopRender struct {
 }
 
 func (n *NopRender) WriteTo(w http.ResponseWriter) error {
-	copyHeaders(w.Header(), n.Headers)
+	n.writeHeader(w, -1)
+	return nil
+}
 
-	if n.Status > 0 {
-		w.WriteHeader(n.Status)
-	} else {
-		w.WriteHeader(http.StatusOK)
+// writeHeader copies the render headers and writes the status code. The
+// Content-Length is set to size unless it's negative or already present.
+// It reports whether the status allows a body, renders skipping theirs
+// otherwise.
+func (n *NopRender) writeHeader(w http.ResponseWriter, size int64) bool {
+	status := n.Status
+	if status <= 0 {
+		status = http.StatusOK
 	}
-	return nil
+
+	copyHeaders(w.Header(), n.Headers)
+	if !shouldWriteBody(status) {
+		w.Header().Del(HeaderContentTyp)
+		w.Header().Del(HeaderContentLen)
+		w.WriteHeader(status)
+		return false
+	}
+	if size >= 0 && w.Header().Get(HeaderContentLen) == "" {
+		w.Header().Set(HeaderContentLen, strconv.FormatInt(size, 10))
+	}
+	w.WriteHeader(status)
+	return true
+}
+
+// shouldWriteBody reports whether a response with the status may have a
+// body, which RFC 9110 forbids for 1xx, 204 No Content and 304 Not Modified.
+// The body of responses to HEAD requests is dropped by
+// clover.HandlerFunc, keeping the headers of the GET response.
+func shouldWriteBody(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
 }
 
 type JSONRender struct {
@@ -88,9 +366,10 @@ type JSONRender struct {
 }
 
 func (j *JSONRender) WriteTo(w http.ResponseWriter) error {
-	_ = j.NopRender.WriteTo(w)
-	_, errW := w.Write(j.Data)
-	return errW
+	if !j.writeHeader(w, int64(len(j.Data))) {
+		return nil
+	}
+	return writeBody(w, j.Data)
 }
 
 type TextRender struct {
@@ -99,9 +378,10 @@ type TextRender struct {
 }
 
 func (t *TextRender) WriteTo(w http.ResponseWriter) error {
-	_ = t.NopRender.WriteTo(w)
-	_, errW := w.Write(t.Text)
-	return errW
+	if !t.writeHeader(w, int64(len(t.Text))) {
+		return nil
+	}
+	return writeBody(w, t.Text)
 }
 
 type RedirectRender struct {
@@ -110,11 +390,188 @@ type RedirectRender struct {
 }
 
 func (r *RedirectRender) WriteTo(w http.ResponseWriter) error {
-	_ = r.NopRender.WriteTo(w)
+	if !r.writeHeader(w, int64(len(r.Text))) {
+		return nil
+	}
 	_, errW := w.Write(r.Text)
 	return errW
 }
 
+type JSONStreamRender struct {
+	NopRender
+	Ch <-chan interface{}
+}
+
+func (j *JSONStreamRender) WriteTo(w http.ResponseWriter) error {
+	if j.Status > 0 && !shouldWriteBody(j.Status) {
+		j.writeHeader(w, -1)
+		go func() {
+			for range j.Ch {
+			}
+		}()
+		return nil
+	}
+
+	fl, _ := w.(http.Flusher)
+	sep := []byte{'['}
+	for v := range j.Ch {
+		data, err := json.Marshal(v)
+		if err == nil {
+			// the headers are only written along with the first element,
+			// so an error encoding it still renders a proper error status
+			if sep[0] == '[' {
+				j.writeHeader(w, -1)
+			}
+			_, err = w.Write(append(sep, data...))
+		}
+		if err != nil {
+			go func() {
+				for range j.Ch {
+				}
+			}()
+			return err
+		}
+		if fl != nil {
+			fl.Flush()
+		}
+		sep[0] = ','
+	}
+
+	if sep[0] == '[' {
+		j.writeHeader(w, 2)
+		_, errW := w.Write([]byte("[]"))
+		return errW
+	}
+	_, errW := w.Write([]byte{']'})
+	return errW
+}
+
+type AttachmentRender struct {
+	NopRender
+	Data []byte
+}
+
+func (a *AttachmentRender) WriteTo(w http.ResponseWriter) error {
+	if !a.writeHeader(w, int64(len(a.Data))) {
+		return nil
+	}
+	return writeBody(w, a.Data)
+}
+
+type NotModifiedRender struct {
+	NopRender
+}
+
+func (n *NotModifiedRender) WriteTo(w http.ResponseWriter) error {
+	copyHeaders(w.Header(), n.Headers)
+	w.Header().Del(HeaderContentTyp)
+	w.Header().Del(HeaderContentLen)
+	w.WriteHeader(http.StatusNotModified)
+	return nil
+}
+
+type EarlyHintsRender struct {
+	Links []string
+}
+
+func (e *EarlyHintsRender) WriteTo(w http.ResponseWriter) error {
+	for _, link := range e.Links {
+		w.Header().Add("Link", preloadLink(link))
+	}
+	w.WriteHeader(http.StatusEarlyHints)
+	return nil
+}
+
+// preloadLink formats a Link header value preloading the link, guessing its
+// destination from the file extension.
+func preloadLink(link string) string {
+	if strings.HasPrefix(link, "<") {
+		return link
+	}
+	v := "<" + link + ">; rel=preload"
+	ext := link
+	if i := strings.IndexAny(ext, "?#"); i >= 0 {
+		ext = ext[:i]
+	}
+	if i := strings.LastIndexByte(ext, '.'); i >= 0 {
+		ext = strings.ToLower(ext[i+1:])
+	}
+	switch ext {
+	case "css":
+		v += "; as=style"
+	case "js", "mjs":
+		v += "; as=script"
+	case "woff", "woff2", "ttf", "otf":
+		v += "; as=font; crossorigin"
+	case "png", "jpg", "jpeg", "gif", "webp", "avif", "svg":
+		v += "; as=image"
+	}
+	return v
+}
+
+type ReaderRender struct {
+	NopRender
+	Length int64
+	Reader io.Reader
+
+	// Trailers declares the trailer headers sent after the body, whose
+	// values are returned by TrailerFunc once the body has been written.
+	Trailers    []string
+	TrailerFunc func() http.Header
+}
+
+// WithTrailers declares the trailer headers of the response, ie. a checksum
+// of the streamed body, whose values are returned by fn once the body has
+// been written. The body is then sent chunked, without a Content-Length.
+func (r *ReaderRender) WithTrailers(names []string, fn func() http.Header) *ReaderRender {
+	r.Trailers = names
+	r.TrailerFunc = fn
+	return r
+}
+
+func (r *ReaderRender) WriteTo(w http.ResponseWriter) error {
+	if rc, ok := r.Reader.(io.ReadCloser); ok {
+		defer rc.Close()
+	}
+	length := r.Length
+	for _, name := range r.Trailers {
+		w.Header().Add("Trailer", name)
+		length = -1
+	}
+	if !r.writeHeader(w, length) {
+		return nil
+	}
+	_, errW := io.Copy(w, r.Reader)
+	if errW == nil && r.TrailerFunc != nil {
+		for name, values := range r.TrailerFunc() {
+			w.Header()[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	return errW
+}
+
+// writeBody writes data to w, in flushed chunks when it's larger than the
+// FlushThreshold.
+func writeBody(w http.ResponseWriter, data []byte) error {
+	fl, ok := w.(http.Flusher)
+	if !ok || FlushThreshold <= 0 || len(data) <= FlushThreshold {
+		_, errW := w.Write(data)
+		return errW
+	}
+	for len(data) > 0 {
+		n := FlushThreshold
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, errW := w.Write(data[:n]); errW != nil {
+			return errW
+		}
+		fl.Flush()
+		data = data[n:]
+	}
+	return nil
+}
+
 func copyHeaders(dst http.Header, src http.Header) {
 	for k, vs := range src {
 		for _, v := range vs {
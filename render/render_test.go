@@ -0,0 +1,499 @@
+package render
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRedirectHelpers(t *testing.T) {
+	tests := []struct {
+		name   string
+		render Render
+		status int
+	}{
+		{"temporary", RedirectTemporary("/login"), http.StatusFound},
+		{"permanent", RedirectPermanent("/login"), http.StatusMovedPermanently},
+		{"see other", RedirectSeeOther("/login"), http.StatusSeeOther},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if err := tc.render.WriteTo(w); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, w.Code)
+			}
+			if loc := w.Header().Get(HeaderLocation); loc != "/login" {
+				t.Fatalf("unexpected location: %q", loc)
+			}
+			if w.Body.Len() != 0 || w.Header().Get(HeaderContentTyp) != "" || w.Header().Get(HeaderContentLen) != "0" {
+				t.Fatalf("expected no body, got %v %q", w.Header(), w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRedirectText(t *testing.T) {
+	w := httptest.NewRecorder()
+	Redirect(http.StatusFound, "/login", "see /login").WriteTo(w)
+	if w.Body.String() != "see /login" || w.Header().Get(HeaderContentTyp) != "text/plain; charset=utf-8" {
+		t.Fatalf("unexpected response: %v %q", w.Header(), w.Body.String())
+	}
+
+	defer func(text string) { RedirectText = text }(RedirectText)
+	RedirectText = "redirect"
+	w = httptest.NewRecorder()
+	RedirectTemporary("/login").WriteTo(w)
+	if w.Body.String() != "redirect" || w.Header().Get(HeaderContentLen) != "8" {
+		t.Fatalf("unexpected response: %v %q", w.Header(), w.Body.String())
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	allowed := []string{"example.com", "auth.example.com"}
+	tests := []struct {
+		location string
+		expected string
+	}{
+		{"/account", "/account"},
+		{"account?tab=1", "/"},
+		{"https://example.com/home", "https://example.com/home"},
+		{"http://AUTH.example.com:8080/cb", "http://AUTH.example.com:8080/cb"},
+		{"https://evil.com/phish", "/"},
+		{"//evil.com/phish", "/"},
+		{"/\\evil.com/phish", "/"},
+		{"javascript:alert(1)", "/"},
+		{"https://example.com.evil.com/", "/"},
+		{" //evil.com", "/"},
+		{"/account ", "/"},
+		{"\t//evil.com", "/"},
+		{"/\t/evil.com", "/"},
+		{"/\n/evil.com", "/"},
+		{"\\\\evil.com", "/"},
+		{"evil.com", "/"},
+		{"https:evil.com", "/"},
+		{"/", "/"},
+	}
+
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		SafeRedirect(allowed, tc.location).WriteTo(w)
+		if w.Code != http.StatusFound {
+			t.Fatalf("%s: expected status 302, got %d", tc.location, w.Code)
+		}
+		if loc := w.Header().Get(HeaderLocation); loc != tc.expected {
+			t.Fatalf("%s: expected location %q, got %q", tc.location, tc.expected, loc)
+		}
+	}
+}
+
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestReader(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := Reader(http.StatusOK, "text/csv", 11, strings.NewReader("id,name\n1,a")).WriteTo(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != "id,name\n1,a" {
+		t.Fatalf("unexpected body: %q", w.Body.String())
+	}
+	if ct := w.Header().Get(HeaderContentTyp); ct != "text/csv" {
+		t.Fatalf("unexpected content type: %q", ct)
+	}
+	if cl := w.Header().Get(HeaderContentLen); cl != "11" {
+		t.Fatalf("unexpected content length: %q", cl)
+	}
+
+	rc := &closeTracker{Reader: strings.NewReader("streamed")}
+	w = httptest.NewRecorder()
+	if err := Reader(http.StatusCreated, "text/plain", -1, rc).WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusCreated || w.Body.String() != "streamed" {
+		t.Fatalf("unexpected response: %d %q", w.Code, w.Body.String())
+	}
+	if cl := w.Header().Get(HeaderContentLen); cl != "" {
+		t.Fatalf("expected no content length, got %q", cl)
+	}
+	if !rc.closed {
+		t.Fatal("expected the reader to be closed")
+	}
+}
+
+func TestJSONStream(t *testing.T) {
+	type item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	ch := make(chan interface{})
+	go func() {
+		defer close(ch)
+		for i := 1; i <= 3; i++ {
+			ch <- item{ID: i, Name: strconv.Itoa(i * 10)}
+		}
+	}()
+
+	w := httptest.NewRecorder()
+	if err := JSONStream(ch).WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if body := w.Body.String(); body != `[{"id":1,"name":"10"},{"id":2,"name":"20"},{"id":3,"name":"30"}]` {
+		t.Fatalf("unexpected body: %q", body)
+	}
+	if ct := w.Header().Get(HeaderContentTyp); ct != "application/json; charset=utf-8" {
+		t.Fatalf("unexpected content type: %q", ct)
+	}
+	if cl := w.Header().Get(HeaderContentLen); cl != "" {
+		t.Fatalf("expected no content length, got %q", cl)
+	}
+	if !w.Flushed {
+		t.Fatal("expected the elements to be flushed")
+	}
+
+	// empty channel
+	empty := make(chan interface{})
+	close(empty)
+	w = httptest.NewRecorder()
+	if err := JSONStream(empty).WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || w.Body.String() != "[]" {
+		t.Fatalf("unexpected response: %d %q", w.Code, w.Body.String())
+	}
+
+	// encode error mid-stream, the producer isn't left blocked
+	ch = make(chan interface{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(ch)
+		ch <- item{ID: 1}
+		ch <- func() {}
+		ch <- item{ID: 2}
+	}()
+	w = httptest.NewRecorder()
+	if err := JSONStream(ch).WriteTo(w); err == nil {
+		t.Fatal("expected an encoding error")
+	}
+	if body := w.Body.String(); body != `[{"id":1,"name":""}` {
+		t.Fatalf("unexpected body: %q", body)
+	}
+	<-done
+}
+
+func TestAttachment(t *testing.T) {
+	pdf := []byte("%PDF-1.7")
+	tests := []struct {
+		disposition string
+		filename    string
+		expected    string
+	}{
+		{"inline", "invoice.pdf", `inline; filename="invoice.pdf"`},
+		{"INLINE", "invoice.pdf", `inline; filename="invoice.pdf"`},
+		{"attachment", "invoice.pdf", `attachment; filename="invoice.pdf"`},
+		{"attachment", "facture n°1.pdf", `attachment; filename="facture n_1.pdf"; filename*=UTF-8''facture%20n%C2%B01.pdf`},
+		{"bogus", "invoice.pdf", `attachment; filename="invoice.pdf"`},
+	}
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		if err := Attachment(tc.disposition, tc.filename, "application/pdf", pdf).WriteTo(w); err != nil {
+			t.Fatal(err)
+		}
+		if cd := w.Header().Get("Content-Disposition"); cd != tc.expected {
+			t.Fatalf("%s %q: expected %q, got %q", tc.disposition, tc.filename, tc.expected, cd)
+		}
+		if w.Code != http.StatusOK || w.Body.String() != "%PDF-1.7" {
+			t.Fatalf("unexpected response: %d %q", w.Code, w.Body.String())
+		}
+		if w.Header().Get(HeaderContentTyp) != "application/pdf" || w.Header().Get(HeaderContentLen) != "8" {
+			t.Fatalf("unexpected headers: %v", w.Header())
+		}
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := ProblemJSON(http.StatusNotFound, Problem{
+		Detail:   "user 42 doesn't exist",
+		Instance: "/users/42",
+	}).WriteTo(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+	if ct := w.Header().Get(HeaderContentTyp); ct != "application/problem+json" {
+		t.Fatalf("unexpected content type: %q", ct)
+	}
+	expected := `{"type":"about:blank","title":"Not Found","status":404,"detail":"user 42 doesn't exist","instance":"/users/42"}`
+	if w.Body.String() != expected {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	ProblemJSON(http.StatusUnprocessableEntity, Problem{
+		Type:       "https://example.com/probs/invalid",
+		Title:      "Invalid params",
+		Status:     http.StatusTeapot,
+		Extensions: map[string]interface{}{"fields": []string{"name"}, "status": 0},
+	}).WriteTo(w)
+	expected = `{"fields":["name"],"status":422,"title":"Invalid params","type":"https://example.com/probs/invalid"}`
+	if w.Code != http.StatusUnprocessableEntity || w.Body.String() != expected {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestReaderTrailers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sum := 0
+		body := io.TeeReader(strings.NewReader("streamed body"), writerFunc(func(p []byte) (int, error) {
+			sum += len(p)
+			return len(p), nil
+		}))
+		Reader(http.StatusOK, "text/plain", 13, body).WithTrailers([]string{"X-Checksum"}, func() http.Header {
+			return http.Header{"X-Checksum": []string{strconv.Itoa(sum)}}
+		}).WriteTo(w)
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, ok := resp.Trailer["X-Checksum"]; !ok {
+		t.Fatalf("expected the X-Checksum trailer to be declared, got %v", resp.Trailer)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "streamed body" {
+		t.Fatalf("unexpected body: %q", body)
+	}
+	if v := resp.Trailer.Get("X-Checksum"); v != "13" {
+		t.Fatalf("unexpected X-Checksum trailer: %q", v)
+	}
+}
+
+func TestEarlyHints(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		EarlyHints("/app.css", "/app.js?v=2", "</font.woff2>; rel=preload; as=font").WriteTo(w)
+		Text("page").WriteTo(w)
+	}))
+	defer ts.Close()
+
+	var hints []http.Header
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusEarlyHints {
+				hints = append(hints, http.Header(header))
+			}
+			return nil
+		},
+	}
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if len(hints) != 1 {
+		t.Fatalf("expected a 103 response, got %d", len(hints))
+	}
+	expected := []string{
+		"</app.css>; rel=preload; as=style",
+		"</app.js?v=2>; rel=preload; as=script",
+		"</font.woff2>; rel=preload; as=font",
+	}
+	if links := hints[0].Values("Link"); strings.Join(links, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected links: %v", links)
+	}
+	if body, _ := io.ReadAll(resp.Body); resp.StatusCode != 200 || string(body) != "page" {
+		t.Fatalf("unexpected final response: %d %q", resp.StatusCode, body)
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+func TestFlushThreshold(t *testing.T) {
+	defer func(n int) { FlushThreshold = n }(FlushThreshold)
+	text := strings.Repeat("a", 2500)
+
+	FlushThreshold = 0
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	Text(text).WriteTo(w)
+	if w.flushes != 0 || w.Body.String() != text {
+		t.Fatalf("expected a single write, got %d flushes", w.flushes)
+	}
+
+	FlushThreshold = 1000
+	w = &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	Text(text).WriteTo(w)
+	if w.flushes != 3 || w.Body.String() != text {
+		t.Fatalf("expected 3 flushed chunks, got %d flushes", w.flushes)
+	}
+	if cl := w.Header().Get(HeaderContentLen); cl != "2500" {
+		t.Fatalf("unexpected content length: %q", cl)
+	}
+
+	w = &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	Text("small").WriteTo(w)
+	if w.flushes != 0 {
+		t.Fatalf("expected small bodies to be written at once, got %d flushes", w.flushes)
+	}
+}
+
+func BenchmarkJSONFlush(b *testing.B) {
+	defer func(n int) { FlushThreshold = n }(FlushThreshold)
+
+	items := make([]map[string]interface{}, 10000)
+	for i := range items {
+		items[i] = map[string]interface{}{"id": i, "name": "clover"}
+	}
+	r := JSON(items)
+
+	for _, threshold := range []int{0, 4096, 32768} {
+		b.Run("threshold:"+strconv.Itoa(threshold), func(b *testing.B) {
+			FlushThreshold = threshold
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.WriteTo(&flushRecorder{ResponseRecorder: httptest.NewRecorder()})
+			}
+		})
+	}
+}
+
+func TestTextWith(t *testing.T) {
+	w := httptest.NewRecorder()
+	TextWith("text/html; charset=utf-8", "<h1>clover</h1>").WriteTo(w)
+	if ct := w.Header().Get(HeaderContentTyp); ct != "text/html; charset=utf-8" {
+		t.Fatalf("unexpected content type: %q", ct)
+	}
+	if w.Body.String() != "<h1>clover</h1>" {
+		t.Fatalf("unexpected body: %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	Text("plain").WriteTo(w)
+	if ct := w.Header().Get(HeaderContentTyp); ct != "text/plain; charset=utf-8" {
+		t.Fatalf("unexpected content type: %q", ct)
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set(HeaderContentTyp, "application/json")
+	w.Header().Set(HeaderContentLen, "42")
+
+	r := NotModified()
+	r.Headers.Set("ETag", `"v1"`)
+	if err := r.WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Fatalf("expected no body, got %q", w.Body.String())
+	}
+	if w.Header().Get(HeaderContentTyp) != "" || w.Header().Get(HeaderContentLen) != "" {
+		t.Fatalf("unexpected headers: %v", w.Header())
+	}
+	if etag := w.Header().Get("ETag"); etag != `"v1"` {
+		t.Fatalf("unexpected etag: %q", etag)
+	}
+}
+
+func TestBodyForbiddenStatus(t *testing.T) {
+	withStatus := func(status int) []struct {
+		name   string
+		render Render
+	} {
+		j := JSON(map[string]bool{"ok": true})
+		j.Status = status
+		txt := Text("ok")
+		txt.Status = status
+		rd := Reader(status, "application/octet-stream", 2, strings.NewReader("ok"))
+		a := Attachment("attachment", "ok.txt", "text/plain", []byte("ok"))
+		a.Status = status
+		return []struct {
+			name   string
+			render Render
+		}{
+			{"json", j},
+			{"text", txt},
+			{"reader", rd},
+			{"attachment", a},
+		}
+	}
+
+	for _, status := range []int{http.StatusNoContent, http.StatusNotModified} {
+		for _, tc := range withStatus(status) {
+			w := httptest.NewRecorder()
+			if err := tc.render.WriteTo(w); err != nil {
+				t.Fatalf("%d %s: %v", status, tc.name, err)
+			}
+			if w.Code != status {
+				t.Fatalf("%d %s: unexpected status %d", status, tc.name, w.Code)
+			}
+			if w.Body.Len() != 0 {
+				t.Fatalf("%d %s: expected no body, got %q", status, tc.name, w.Body.String())
+			}
+			if cl := w.Header().Get("Content-Length"); cl != "" {
+				t.Fatalf("%d %s: expected no Content-Length, got %q", status, tc.name, cl)
+			}
+		}
+	}
+
+	// over a real connection, where writing the body would fail
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		j := JSON(map[string]bool{"ok": true})
+		j.Status = http.StatusNoContent
+		if err := j.WriteTo(w); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent || len(body) != 0 {
+		t.Fatalf("unexpected response: %d %q", res.StatusCode, body)
+	}
+}
@@ -0,0 +1,178 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sync"
+)
+
+// TemplatesOpts represents a set of template options.
+type TemplatesOpts struct {
+	// Layout is the base layout every page is executed within, ie.
+	// "layouts/base.html", which includes the page content with
+	// {{block "content" .}}{{end}}, defined by the pages. Pages are
+	// executed on their own if empty.
+	Layout string
+
+	// Partials are the glob patterns of the templates shared by the layout
+	// and the pages, ie. "partials/*.html".
+	Partials []string
+
+	// Pages are the glob patterns of the pages, "*.html" if empty.
+	Pages []string
+
+	// Funcs are the functions available to the templates.
+	Funcs template.FuncMap
+
+	// Reload parses the templates again on each View, to pick up changes
+	// during development.
+	Reload bool
+}
+
+// TemplateSet holds the parsed pages of a template directory, each composed
+// with the layout and the partials.
+type TemplateSet struct {
+	fsys fs.FS
+	opts TemplatesOpts
+
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+// DefaultTemplates is the TemplateSet used by View.
+var DefaultTemplates *TemplateSet
+
+// Templates parses the templates of fsys once, composing each page with the
+// layout and partials of opts, ie.
+//
+//	ts, err := render.Templates(os.DirFS("views"), render.TemplatesOpts{
+//		Layout:   "layouts/base.html",
+//		Partials: []string{"partials/*.html"},
+//		Pages:    []string{"pages/*.html"},
+//	})
+//	...
+//	return ts.View("pages/home.html", data)
+func Templates(fsys fs.FS, opts TemplatesOpts) (*TemplateSet, error) {
+	if len(opts.Pages) == 0 {
+		opts.Pages = []string{"*.html"}
+	}
+	ts := &TemplateSet{fsys: fsys, opts: opts}
+	if err := ts.load(); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// load parses the layout and partials, and then each page on a copy of them.
+func (ts *TemplateSet) load() error {
+	base := template.New("").Funcs(ts.opts.Funcs)
+	var shared []string
+	if ts.opts.Layout != "" {
+		shared = append(shared, ts.opts.Layout)
+	}
+	for _, pattern := range ts.opts.Partials {
+		matches, err := fs.Glob(ts.fsys, pattern)
+		if err != nil {
+			return err
+		}
+		shared = append(shared, matches...)
+	}
+	if len(shared) > 0 {
+		if _, err := base.ParseFS(ts.fsys, shared...); err != nil {
+			return err
+		}
+	}
+
+	pages := make(map[string]*template.Template)
+	for _, pattern := range ts.opts.Pages {
+		matches, err := fs.Glob(ts.fsys, pattern)
+		if err != nil {
+			return err
+		}
+		for _, page := range matches {
+			if page == ts.opts.Layout {
+				continue
+			}
+			t, err := base.Clone()
+			if err != nil {
+				return err
+			}
+			if pages[page], err = t.ParseFS(ts.fsys, page); err != nil {
+				return err
+			}
+		}
+	}
+
+	ts.mu.Lock()
+	ts.pages = pages
+	ts.mu.Unlock()
+	return nil
+}
+
+// View renders the page at the name path of the template directory within
+// the layout, as text/html.
+func (ts *TemplateSet) View(name string, data interface{}) *ViewRender {
+	return &ViewRender{
+		NopRender: NopRender{
+			Status: http.StatusOK,
+			Headers: http.Header{
+				HeaderContentTyp: []string{"text/html; charset=utf-8"},
+			},
+		},
+		Templates: ts,
+		Name:      name,
+		Data:      data,
+	}
+}
+
+// View renders the page at the name path within the layout of the
+// DefaultTemplates.
+var View = func(name string, data interface{}) *ViewRender {
+	return DefaultTemplates.View(name, data)
+}
+
+type ViewRender struct {
+	NopRender
+	Templates *TemplateSet
+	Name      string
+	Data      interface{}
+}
+
+// WriteTo executes the page before writing anything, so a failing template
+// doesn't send a partial page.
+func (v *ViewRender) WriteTo(w http.ResponseWriter) error {
+	ts := v.Templates
+	if ts == nil {
+		return fmt.Errorf("render: no templates to render view %q", v.Name)
+	}
+	if ts.opts.Reload {
+		if err := ts.load(); err != nil {
+			return err
+		}
+	}
+
+	ts.mu.RLock()
+	t := ts.pages[v.Name]
+	ts.mu.RUnlock()
+	if t == nil {
+		return fmt.Errorf("render: view %q not found", v.Name)
+	}
+
+	name := path.Base(v.Name)
+	if ts.opts.Layout != "" {
+		name = path.Base(ts.opts.Layout)
+	}
+	buf := &bytes.Buffer{}
+	if err := t.ExecuteTemplate(buf, name, v.Data); err != nil {
+		return err
+	}
+
+	if !v.writeHeader(w, int64(buf.Len())) {
+		return nil
+	}
+	return writeBody(w, buf.Bytes())
+}
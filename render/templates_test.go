@@ -0,0 +1,86 @@
+package render
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html": {Data: []byte(`<title>{{block "title" .}}Site{{end}}</title>{{template "nav" .}}<main>{{block "content" .}}{{end}}</main>`)},
+		"partials/nav.html": {Data: []byte(`{{define "nav"}}<nav>{{.User}}</nav>{{end}}`)},
+		"pages/home.html":   {Data: []byte(`{{define "content"}}<p>Hello {{.User}}</p>{{end}}`)},
+		"pages/about.html":  {Data: []byte(`{{define "title"}}About{{end}}{{define "content"}}{{upper "about"}}{{end}}`)},
+		"pages/broken.html": {Data: []byte(`{{define "content"}}partial{{fail}}{{end}}`)},
+	}
+
+	ts, err := Templates(fsys, TemplatesOpts{
+		Layout:   "layouts/base.html",
+		Partials: []string{"partials/*.html"},
+		Pages:    []string{"pages/*.html"},
+		Funcs: map[string]interface{}{
+			"upper": strings.ToUpper,
+			"fail":  func() (string, error) { return "", errors.New("boom") },
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	if err := ts.View("pages/home.html", map[string]string{"User": "<clover>"}).WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if body := w.Body.String(); body != `<title>Site</title><nav>&lt;clover&gt;</nav><main><p>Hello &lt;clover&gt;</p></main>` {
+		t.Fatalf("unexpected body: %q", body)
+	}
+	if ct := w.Header().Get(HeaderContentTyp); ct != "text/html; charset=utf-8" {
+		t.Fatalf("unexpected content type: %q", ct)
+	}
+
+	w = httptest.NewRecorder()
+	if err := ts.View("pages/about.html", map[string]string{"User": "x"}).WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if body := w.Body.String(); body != `<title>About</title><nav>x</nav><main>ABOUT</main>` {
+		t.Fatalf("unexpected body: %q", body)
+	}
+
+	w = httptest.NewRecorder()
+	if err := ts.View("pages/missing.html", nil).WriteTo(w); err == nil {
+		t.Fatal("expected an error for a missing view")
+	}
+	w = httptest.NewRecorder()
+	if err := ts.View("pages/broken.html", map[string]string{}).WriteTo(w); err == nil || w.Body.Len() != 0 {
+		t.Fatalf("expected an error without a partial page, got %v %q", err, w.Body.String())
+	}
+}
+
+func TestTemplatesReload(t *testing.T) {
+	fsys := fstest.MapFS{
+		"home.html": {Data: []byte(`v1`)},
+	}
+	ts, err := Templates(fsys, TemplatesOpts{Reload: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(d *TemplateSet) { DefaultTemplates = d }(DefaultTemplates)
+	DefaultTemplates = ts
+
+	w := httptest.NewRecorder()
+	View("home.html", nil).WriteTo(w)
+	if w.Body.String() != "v1" {
+		t.Fatalf("unexpected body: %q", w.Body.String())
+	}
+
+	fsys["home.html"] = &fstest.MapFile{Data: []byte(`v2`)}
+	w = httptest.NewRecorder()
+	View("home.html", nil).WriteTo(w)
+	if w.Body.String() != "v2" {
+		t.Fatalf("expected the reloaded template, got %q", w.Body.String())
+	}
+}
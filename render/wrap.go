@@ -0,0 +1,263 @@
+package render
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// WithCookies wraps the inner render, setting each of the cookies on its
+// response with their own Set-Cookie header, ie. for a session and a CSRF
+// cookie set together on login.
+var WithCookies = func(inner Render, cookies ...*http.Cookie) Render {
+	return &CookiesRender{Inner: inner, Cookies: cookies}
+}
+
+type CookiesRender struct {
+	Inner   Render
+	Cookies []*http.Cookie
+}
+
+func (c *CookiesRender) WriteTo(w http.ResponseWriter) error {
+	for _, cookie := range c.Cookies {
+		http.SetCookie(w, cookie)
+	}
+	return c.Inner.WriteTo(w)
+}
+
+// CookieOption sets an attribute of the cookie expired by ClearCookie.
+type CookieOption func(c *http.Cookie)
+
+// CookiePath sets the Path of the cookie, "/" by default.
+func CookiePath(path string) CookieOption {
+	return func(c *http.Cookie) { c.Path = path }
+}
+
+// CookieDomain sets the Domain of the cookie.
+func CookieDomain(domain string) CookieOption {
+	return func(c *http.Cookie) { c.Domain = domain }
+}
+
+// CookieSecure sets the Secure attribute of the cookie, which browsers
+// require to overwrite a cookie that was set with it.
+func CookieSecure() CookieOption {
+	return func(c *http.Cookie) { c.Secure = true }
+}
+
+// ClearCookie returns a cookie that makes browsers delete the cookie name,
+// to be set with WithCookies, ie. on logout:
+//
+//	render.WithCookies(render.RedirectSeeOther("/"), render.ClearCookie("session"))
+//
+// Browsers only delete a cookie when the Path and Domain match the ones it
+// was set with, the Path being "/" unless set with CookiePath.
+func ClearCookie(name string, opts ...CookieOption) *http.Cookie {
+	c := &http.Cookie{
+		Name:    name,
+		Path:    "/",
+		MaxAge:  -1,
+		Expires: time.Unix(0, 0),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithCache wraps the inner render, setting the Cache-Control and Expires
+// headers of its response so shared caches like CDNs may store it for
+// maxAge, ie. `render.WithCache(time.Hour, render.JSON(v))`.
+var WithCache = func(maxAge time.Duration, inner Render) Render {
+	return &CacheRender{Inner: inner, MaxAge: maxAge}
+}
+
+type CacheRender struct {
+	Inner  Render
+	MaxAge time.Duration
+}
+
+func (c *CacheRender) WriteTo(w http.ResponseWriter) error {
+	maxAge := c.MaxAge
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.FormatInt(int64(maxAge/time.Second), 10))
+	w.Header().Set("Expires", time.Now().Add(maxAge).UTC().Format(http.TimeFormat))
+	return c.Inner.WriteTo(w)
+}
+
+// Download wraps the inner render, setting a Content-Disposition header so
+// browsers download its response as filename rather than displaying it, ie.
+// `render.Download("report.csv", render.TextWith("text/csv", csv))`.
+var Download = func(filename string, inner Render) Render {
+	return &DownloadRender{Inner: inner, Filename: filename}
+}
+
+type DownloadRender struct {
+	Inner    Render
+	Filename string
+}
+
+func (d *DownloadRender) WriteTo(w http.ResponseWriter) error {
+	w.Header().Set("Content-Disposition", contentDisposition("attachment", d.Filename))
+	return d.Inner.WriteTo(w)
+}
+
+// WithServerTiming wraps the inner render, reporting the metrics of the
+// request in a Server-Timing header, ie. `db;dur=12.3, render;dur=4.1`, which
+// browser devtools display along with the network timings. Durations are sent
+// in milliseconds, metrics are sorted by name and the names that aren't valid
+// tokens are skipped.
+var WithServerTiming = func(metrics map[string]time.Duration, inner Render) Render {
+	return &ServerTimingRender{Inner: inner, Metrics: metrics}
+}
+
+type ServerTimingRender struct {
+	Inner   Render
+	Metrics map[string]time.Duration
+}
+
+func (s *ServerTimingRender) WriteTo(w http.ResponseWriter) error {
+	names := make([]string, 0, len(s.Metrics))
+	for name := range s.Metrics {
+		if isToken(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		for i, name := range names {
+			ms := float64(s.Metrics[name].Round(time.Microsecond)) / float64(time.Millisecond)
+			names[i] = name + ";dur=" + strconv.FormatFloat(ms, 'f', -1, 64)
+		}
+		w.Header().Add("Server-Timing", strings.Join(names, ", "))
+	}
+	return s.Inner.WriteTo(w)
+}
+
+// WithHeaders wraps the inner render, setting the headers h on its response
+// before the inner render writes it. Headers the inner render sets itself,
+// like the Content-Type of a JSON render, are added to them.
+var WithHeaders = func(h http.Header, inner Render) Render {
+	return &HeadersRender{Inner: inner, Headers: h}
+}
+
+type HeadersRender struct {
+	Inner   Render
+	Headers http.Header
+}
+
+func (h *HeadersRender) WriteTo(w http.ResponseWriter) error {
+	for k, vs := range h.Headers {
+		w.Header()[http.CanonicalHeaderKey(k)] = append([]string(nil), vs...)
+	}
+	return h.Inner.WriteTo(w)
+}
+
+// Compose writes each of the renders in order on the same response, ie.
+// to append a footer to a templated page or to send EarlyHints ahead of a
+// page. Only the first final status is written, the headers being sent
+// with it: the renders after it only add to the body. The Content-Length is
+// dropped as it only accounts for the body of the first render. The first
+// error stops the composition.
+var Compose = func(renders ...Render) Render {
+	return &ComposeRender{Renders: renders}
+}
+
+type ComposeRender struct {
+	Renders []Render
+}
+
+func (c *ComposeRender) WriteTo(w http.ResponseWriter) error {
+	if len(c.Renders) == 1 {
+		return c.Renders[0].WriteTo(w)
+	}
+	cw := &composeWriter{ResponseWriter: w}
+	for _, r := range c.Renders {
+		if err := r.WriteTo(cw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// composeWriter forwards the first WriteHeader only, without the
+// Content-Length of the first render.
+type composeWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (cw *composeWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	// informational responses, ie. from EarlyHints, precede the final one
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.wroteHeader = true
+	cw.Header().Del(HeaderContentLen)
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *composeWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *composeWriter) Flush() {
+	if fl, ok := cw.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+func (cw *composeWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// isToken reports whether s is a valid RFC 7230 token.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x7f || c <= ' ' || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// contentDisposition formats a Content-Disposition header value. Non-ASCII
+// filenames are sent in the RFC 5987 filename* parameter, along with an
+// ASCII fallback for older clients.
+func contentDisposition(disposition, filename string) string {
+	if filename == "" {
+		return disposition
+	}
+
+	ascii := true
+	fallback := strings.Map(func(r rune) rune {
+		if r >= utf8.RuneSelf || r < ' ' || r == 0x7f {
+			ascii = false
+			return '_'
+		}
+		return r
+	}, filename)
+	fallback = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(fallback)
+
+	v := disposition + `; filename="` + fallback + `"`
+	if !ascii {
+		v += "; filename*=UTF-8''" + strings.ReplaceAll(url.PathEscape(filename), "+", "%2B")
+	}
+	return v
+}
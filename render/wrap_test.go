@@ -0,0 +1,194 @@
+package render
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithCookies(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := WithCookies(JSON(map[string]bool{"ok": true}),
+		&http.Cookie{Name: "session", Value: "s1", Path: "/", HttpOnly: true},
+		&http.Cookie{Name: "csrf", Value: "c1", Path: "/"},
+	).WriteTo(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := w.Header().Values("Set-Cookie")
+	if len(cookies) != 2 {
+		t.Fatalf("expected 2 Set-Cookie headers, got %v", cookies)
+	}
+	if cookies[0] != "session=s1; Path=/; HttpOnly" || cookies[1] != "csrf=c1; Path=/" {
+		t.Fatalf("unexpected cookies: %v", cookies)
+	}
+	if w.Body.String() != `{"ok":true}` {
+		t.Fatalf("unexpected body: %q", w.Body.String())
+	}
+
+	// cookies set through the render headers are kept apart as well
+	r := Text("ok")
+	r.Headers.Add("Set-Cookie", "a=1")
+	r.Headers.Add("Set-Cookie", "b=2")
+	w = httptest.NewRecorder()
+	r.WriteTo(w)
+	if cookies := w.Header().Values("Set-Cookie"); len(cookies) != 2 {
+		t.Fatalf("expected 2 Set-Cookie headers, got %v", cookies)
+	}
+}
+
+func TestClearCookie(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := WithCookies(Text("bye"),
+		ClearCookie("session"),
+		ClearCookie("prefs", CookiePath("/app"), CookieDomain("example.com"), CookieSecure()),
+	).WriteTo(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := w.Header().Values("Set-Cookie")
+	if len(cookies) != 2 {
+		t.Fatalf("expected 2 Set-Cookie headers, got %v", cookies)
+	}
+	if cookies[0] != "session=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0" {
+		t.Fatalf("unexpected cookie: %q", cookies[0])
+	}
+	if cookies[1] != "prefs=; Path=/app; Domain=example.com; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0; Secure" {
+		t.Fatalf("unexpected cookie: %q", cookies[1])
+	}
+}
+
+func TestWithCache(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := WithCache(time.Hour, JSON(map[string]bool{"ok": true})).WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=3600" {
+		t.Fatalf("unexpected Cache-Control: %q", cc)
+	}
+	expires, err := http.ParseTime(w.Header().Get("Expires"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(expires); d < 59*time.Minute || d > time.Hour {
+		t.Fatalf("unexpected Expires: %v", expires)
+	}
+	if w.Header().Get("Content-Type") != "application/json; charset=utf-8" || w.Body.String() != `{"ok":true}` {
+		t.Fatalf("unexpected response: %v %q", w.Header(), w.Body.String())
+	}
+}
+
+func TestWithHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-Request-Id", "r1")
+	h.Add("Link", "</a>; rel=preload")
+	h.Add("Link", "</b>; rel=preload")
+
+	w := httptest.NewRecorder()
+	if err := WithHeaders(h, JSON(map[string]bool{"ok": true})).WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Header().Get("X-Request-Id") != "r1" || len(w.Header().Values("Link")) != 2 {
+		t.Fatalf("expected the headers to be merged, got %v", w.Header())
+	}
+	if w.Header().Get("Content-Type") != "application/json; charset=utf-8" || w.Body.String() != `{"ok":true}` {
+		t.Fatalf("unexpected response: %v %q", w.Header(), w.Body.String())
+	}
+}
+
+func TestCompose(t *testing.T) {
+	w := httptest.NewRecorder()
+	first := Text("head,")
+	first.Status = http.StatusAccepted
+	if err := Compose(first, Text("tail")).WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusAccepted || w.Body.String() != "head,tail" {
+		t.Fatalf("unexpected response: %d %q", w.Code, w.Body.String())
+	}
+	if cl := w.Result().Header.Get("Content-Length"); cl != "" {
+		t.Fatalf("expected no Content-Length, got %q", cl)
+	}
+
+	// the final status follows the early hints
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notFound := Text("not found")
+		notFound.Status = http.StatusNotFound
+		Compose(EarlyHints("/app.css"), notFound).WriteTo(w)
+	}))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound || string(body) != "not found" {
+		t.Fatalf("unexpected response: %d %q", res.StatusCode, body)
+	}
+}
+
+func TestWithServerTiming(t *testing.T) {
+	metrics := map[string]time.Duration{
+		"render":   4100 * time.Microsecond,
+		"db":       12300 * time.Microsecond,
+		"cache":    2 * time.Millisecond,
+		"bad name": time.Second,
+		"total":    1234567 * time.Nanosecond,
+	}
+	w := httptest.NewRecorder()
+	if err := WithServerTiming(metrics, Text("ok")).WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if st := w.Header().Get("Server-Timing"); st != "cache;dur=2, db;dur=12.3, render;dur=4.1, total;dur=1.235" {
+		t.Fatalf("unexpected Server-Timing: %q", st)
+	}
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("unexpected response: %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	if err := WithServerTiming(nil, Text("ok")).WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := w.Header()["Server-Timing"]; ok {
+		t.Fatal("expected no Server-Timing header")
+	}
+}
+
+func TestDownload(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := Download("report.csv", TextWith("text/csv", "id,name\n1,a")).WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="report.csv"` {
+		t.Fatalf("unexpected Content-Disposition: %q", cd)
+	}
+	if w.Header().Get(HeaderContentTyp) != "text/csv" || w.Body.String() != "id,name\n1,a" {
+		t.Fatalf("unexpected response: %v %q", w.Header(), w.Body.String())
+	}
+
+	tests := []struct {
+		filename string
+		expected string
+	}{
+		{`say "hi".txt`, `attachment; filename="say \"hi\".txt"`},
+		{"résumé 2024.pdf", `attachment; filename="r_sum_ 2024.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9%202024.pdf`},
+		{"", "attachment"},
+	}
+	for _, tc := range tests {
+		w = httptest.NewRecorder()
+		Download(tc.filename, JSON(nil)).WriteTo(w)
+		if cd := w.Header().Get("Content-Disposition"); cd != tc.expected {
+			t.Fatalf("%q: expected %q, got %q", tc.filename, tc.expected, cd)
+		}
+	}
+}
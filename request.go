@@ -1,11 +1,17 @@
 package clover
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 )
 
 // Request http server 请求信息
@@ -23,6 +29,23 @@ type Request interface {
 	// 客户端的地址，如 127.0.0.1:12345
 	RemoteAddr() string
 
+	// Referer 请求来源页面的地址
+	Referer() string
+
+	// UserAgent 客户端的 User-Agent
+	UserAgent() string
+
+	// Host 请求的主机名，可能包含端口，如 example.com:8080
+	Host() string
+
+	// Scheme 请求的协议，http 或 https，
+	// 当 TrustForwardedProto 为 true 时，会使用代理设置的 X-Forwarded-Proto 头
+	Scheme() string
+
+	// AbsoluteURL 基于请求的协议和主机名构造完整的URL，用于跳转和邮件中的链接，
+	// 相对路径基于当前请求的路径解析，keepQuery 为 true 且 path 不带参数时，保留当前请求的参数
+	AbsoluteURL(path string, keepQuery bool) string
+
 	// 请求头信息
 	Header(name string) (value string, has bool)
 
@@ -32,6 +55,9 @@ type Request interface {
 	// 获取原始的cookie
 	Cookie(name string) (value *http.Cookie, has bool)
 
+	// BasicAuth 获取 Basic 认证的用户名和密码
+	BasicAuth() (username, password string, ok bool)
+
 	// Query 获取get请求里的参数
 	// 如 xxx?a=v1&b=v2，可获取a、b的值
 	Query(name string) (value string, has bool)
@@ -39,28 +65,129 @@ type Request interface {
 	// QueryDefault 获取get请求里的参数，若name不存在，或者是为空字符串，会返回默认值
 	QueryDefault(name string, defaultValue string) string
 
+	// QueryArray 获取get请求里重复出现的参数的所有值
+	// 如 xxx?id=1&id=2，返回 ["1", "2"]
+	QueryArray(name string) []string
+
+	// QueryMap 获取get请求里以中括号表示的参数
+	// 如 xxx?filter[name]=x&filter[age]=3，QueryMap("filter") 返回 {"name": "x", "age": "3"}
+	QueryMap(prefix string) map[string]string
+
+	// QueryValues 获取get请求里的全部参数
+	QueryValues() url.Values
+
 	PostForm(name string) (value string, has bool)
 
+	// PostFormValues 获取post请求里的全部表单参数
+	PostFormValues() url.Values
+
 	PostFormDefault(name string, defaultValue string) string
 
+	// ParseFormErr 返回解析表单时的错误，如格式错误的请求体，表单只会解析一次
+	ParseFormErr() error
+
 	Param(name string) (value string, has bool)
 
 	ParamDefault(name string, defaultValue string) string
 
 	JsonUnmarshal(dst interface{}) error
 
+	// Bytes 读取完整的请求体，最多读取 maxBytes 字节，超出时返回 ErrBodyTooLarge，
+	// 读取的内容会被缓存，之后的 JsonUnmarshal 等方法会复用
+	Bytes(maxBytes int64) ([]byte, error)
+
+	// BindXML 读取请求体并按 XML 解析到 dst，请求体最多读取 MaxXMLBodyBytes 字节，
+	// 若设置了非 XML 的 Content-Type，返回 ErrNotXML
+	BindXML(dst interface{}) error
+
 	Body() io.ReadCloser
+
+	// Context 返回请求的 context，包含中间件通过 WithValue 设置的值
+	Context() context.Context
+
+	// Deadline 返回请求 context 的截止时间，如 Timeout 中间件设置的超时，
+	// 便于为下游调用预留时间
+	Deadline() (deadline time.Time, ok bool)
 }
 
+var (
+	// MaxXMLBodyBytes caps the request body read by BindXML.
+	MaxXMLBodyBytes int64 = 10 << 20
+
+	// TrustForwardedProto controls whether Request.Scheme trusts the
+	// X-Forwarded-Proto header, which must only be enabled behind a TLS
+	// terminating proxy that sets it.
+	TrustForwardedProto = false
+
+	// ErrBodyTooLarge is returned by Request.Bytes and BindXML when the
+	// request body exceeds their limit.
+	ErrBodyTooLarge = errors.New("clover: request body too large")
+
+	// ErrNotXML is returned by BindXML when the request has a Content-Type
+	// other than an XML one.
+	ErrNotXML = errors.New("clover: request content type is not XML")
+)
+
 // NewRequest 基于原生的request创建一个封装更多功能的request
 func NewRequest(req *http.Request) Request {
 	return &request{raw: req}
 }
 
+var (
+	// RequestCtxKey is the context.Context key to store the Request wrapper.
+	RequestCtxKey = &contextKey{"Request"}
+)
+
+// WithRequest returns a shallow copy of r whose context carries a Request
+// wrapper around it, retrievable with ReqOf. Building the wrapper once lets
+// every handler share its memoized query and buffered body.
+func WithRequest(r *http.Request) *http.Request {
+	req := &request{}
+	req.raw = r.WithContext(context.WithValue(r.Context(), RequestCtxKey, req))
+	return req.raw
+}
+
+// ReqOf returns the Request wrapper stored in ctx by WithRequest, or nil
+// if there is none.
+func ReqOf(ctx context.Context) Request {
+	req, _ := ctx.Value(RequestCtxKey).(Request)
+	return req
+}
+
+// WithValue returns a shallow copy of r whose context carries val for key,
+// the way middlewares pass values down to the handlers. When r carries a
+// Request wrapper stored by WithRequest, the copy gets a fresh wrapper with
+// the same memoized query, body and form, so its Context reads the value
+// too while the wrapper seen by the outer handlers stays untouched.
+//
+//	func Auth(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			next.ServeHTTP(w, clover.WithValue(r, userKey, user))
+//		})
+//	}
+//
+//	func profile(ctx context.Context, r *http.Request) render.Render {
+//		user := ctx.Value(userKey).(*User)
+//		...
+//	}
+func WithValue(r *http.Request, key, val interface{}) *http.Request {
+	ctx := context.WithValue(r.Context(), key, val)
+	req, ok := ctx.Value(RequestCtxKey).(*request)
+	if !ok {
+		return r.WithContext(ctx)
+	}
+	nreq := *req
+	nreq.raw = r.WithContext(context.WithValue(ctx, RequestCtxKey, &nreq))
+	return nreq.raw
+}
+
 type request struct {
 	raw      *http.Request
 	urlQuery url.Values
 	body     []byte
+
+	formParsed bool
+	formErr    error
 }
 
 func (req *request) HTTPRequest() *http.Request {
@@ -79,6 +206,47 @@ func (req *request) RemoteAddr() string {
 	return req.raw.RemoteAddr
 }
 
+func (req *request) Referer() string {
+	return req.raw.Referer()
+}
+
+func (req *request) UserAgent() string {
+	return req.raw.UserAgent()
+}
+
+func (req *request) Host() string {
+	return req.raw.Host
+}
+
+func (req *request) Scheme() string {
+	if req.raw.TLS != nil {
+		return "https"
+	}
+	if TrustForwardedProto {
+		proto := req.raw.Header.Get("X-Forwarded-Proto")
+		if i := strings.IndexByte(proto, ','); i >= 0 {
+			proto = proto[:i]
+		}
+		if strings.EqualFold(strings.TrimSpace(proto), "https") {
+			return "https"
+		}
+	}
+	return "http"
+}
+
+func (req *request) AbsoluteURL(path string, keepQuery bool) string {
+	base := &url.URL{Scheme: req.Scheme(), Host: req.raw.Host, Path: req.raw.URL.Path}
+	ref, err := url.Parse(path)
+	if err != nil {
+		ref = &url.URL{Path: path}
+	}
+	u := base.ResolveReference(ref)
+	if keepQuery && ref.RawQuery == "" {
+		u.RawQuery = req.raw.URL.RawQuery
+	}
+	return u.String()
+}
+
 func (req *request) Header(name string) (value string, has bool) {
 	vs := req.raw.Header.Values(name)
 	if len(vs) == 0 {
@@ -101,17 +269,48 @@ func (req *request) Cookie(name string) (value *http.Cookie, has bool) {
 	}
 	return cookie, true
 }
-func (req *request) Query(name string) (value string, has bool) {
+func (req *request) query() url.Values {
 	if req.urlQuery == nil {
 		req.urlQuery = req.raw.URL.Query()
 	}
-	values := req.urlQuery[name]
+	return req.urlQuery
+}
+
+func (req *request) BasicAuth() (username, password string, ok bool) {
+	return req.raw.BasicAuth()
+}
+
+func (req *request) Query(name string) (value string, has bool) {
+	values := req.query()[name]
 	if len(values) == 0 {
 		return "", false
 	}
 	return values[0], true
 }
 
+func (req *request) QueryValues() url.Values {
+	return req.query()
+}
+
+func (req *request) QueryArray(name string) []string {
+	return req.query()[name]
+}
+
+func (req *request) QueryMap(prefix string) map[string]string {
+	m := make(map[string]string)
+	for k, vs := range req.query() {
+		if len(vs) == 0 || !strings.HasPrefix(k, prefix+"[") || !strings.HasSuffix(k, "]") {
+			continue
+		}
+		key := k[len(prefix)+1 : len(k)-1]
+		if key == "" {
+			continue
+		}
+		m[key] = vs[0]
+	}
+	return m
+}
+
 func (req *request) QueryDefault(name string, defaultValue string) string {
 	if v, _ := req.Query(name); v != "" {
 		return v
@@ -133,8 +332,21 @@ func (req *request) ParamDefault(name string, defaultValue string) string {
 	return defaultValue
 }
 
+// parseForm parses the form of the request once, memoizing the error.
+func (req *request) parseForm() error {
+	if !req.formParsed {
+		req.formErr = req.raw.ParseForm()
+		req.formParsed = true
+	}
+	return req.formErr
+}
+
+func (req *request) ParseFormErr() error {
+	return req.parseForm()
+}
+
 func (req *request) PostForm(name string) (value string, has bool) {
-	_ = req.raw.ParseForm()
+	_ = req.parseForm()
 	vs := req.raw.PostForm[name]
 	if len(vs) == 0 {
 		return "", false
@@ -142,6 +354,11 @@ func (req *request) PostForm(name string) (value string, has bool) {
 	return vs[0], true
 }
 
+func (req *request) PostFormValues() url.Values {
+	_ = req.parseForm()
+	return req.raw.PostForm
+}
+
 func (req *request) PostFormDefault(name string, defaultValue string) string {
 	if v, _ := req.PostForm(name); v != "" {
 		return v
@@ -158,10 +375,62 @@ func (req *request) JsonUnmarshal(dst interface{}) (err error) {
 	return json.Unmarshal(req.body, dst)
 }
 
+func (req *request) BindXML(dst interface{}) error {
+	if ct := req.raw.Header.Get("Content-Type"); ct != "" && !isXMLContentType(ct) {
+		return ErrNotXML
+	}
+	body, err := req.Bytes(MaxXMLBodyBytes)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(body, dst)
+}
+
+func (req *request) Bytes(maxBytes int64) ([]byte, error) {
+	if len(req.body) <= 0 {
+		body, err := io.ReadAll(io.LimitReader(req.Body(), maxBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(body)) > maxBytes {
+			// 把已读取的部分放回去，后续仍可完整读取请求体
+			raw := req.raw.Body
+			req.raw.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), raw), raw}
+			return nil, ErrBodyTooLarge
+		}
+		req.body = body
+	}
+	if int64(len(req.body)) > maxBytes {
+		return nil, ErrBodyTooLarge
+	}
+	return req.body, nil
+}
+
+// isXMLContentType reports whether ct is application/xml, text/xml or an
+// XML based media type such as application/soap+xml.
+func isXMLContentType(ct string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mt == "application/xml" || mt == "text/xml" || strings.HasSuffix(mt, "+xml")
+}
+
 func (req *request) Body() io.ReadCloser {
 	return req.raw.Body
 }
 
+func (req *request) Context() context.Context {
+	return req.raw.Context()
+}
+
+func (req *request) Deadline() (time.Time, bool) {
+	return req.raw.Context().Deadline()
+}
+
 func (req *request) WithContext(ctx context.Context) Request {
 	r2 := new(request)
 	*r2 = *req
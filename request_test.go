@@ -0,0 +1,270 @@
+package clover
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goclover/clover/render"
+)
+
+func TestRequestAccessors(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com:8080/login?next=/home", nil)
+	r.Header.Set("Referer", "http://example.com/home")
+	r.Header.Set("User-Agent", "clover-test/1.0")
+
+	req := NewRequest(r)
+	if v := req.Referer(); v != "http://example.com/home" {
+		t.Fatalf("unexpected referer: %q", v)
+	}
+	if v := req.UserAgent(); v != "clover-test/1.0" {
+		t.Fatalf("unexpected user agent: %q", v)
+	}
+	if v := req.Host(); v != "example.com:8080" {
+		t.Fatalf("unexpected host: %q", v)
+	}
+}
+
+func TestRequestQueryArrayAndMap(t *testing.T) {
+	r := httptest.NewRequest("GET", "/users?id=1&id=2&filter[name]=x&filter[age]=3&filter[]=skip&filter=plain&other[name]=y", nil)
+	req := NewRequest(r)
+
+	ids := req.QueryArray("id")
+	if len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+		t.Fatalf("unexpected ids: %v", ids)
+	}
+	if v := req.QueryArray("missing"); len(v) != 0 {
+		t.Fatalf("expected no values, got %v", v)
+	}
+
+	filter := req.QueryMap("filter")
+	if len(filter) != 2 || filter["name"] != "x" || filter["age"] != "3" {
+		t.Fatalf("unexpected filter: %v", filter)
+	}
+	if v := req.QueryMap("missing"); len(v) != 0 {
+		t.Fatalf("expected an empty map, got %v", v)
+	}
+}
+
+func TestRequestBasicAuth(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if _, _, ok := NewRequest(r).BasicAuth(); ok {
+		t.Fatal("expected no basic auth credentials")
+	}
+
+	r.SetBasicAuth("clover", "s3cret")
+	user, pass, ok := NewRequest(r).BasicAuth()
+	if !ok || user != "clover" || pass != "s3cret" {
+		t.Fatalf("unexpected credentials: %q %q %v", user, pass, ok)
+	}
+}
+
+func TestRequestValues(t *testing.T) {
+	r := httptest.NewRequest("POST", "/users?a=1&a=2&b=3", strings.NewReader("name=clover&tag=x&tag=y"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req := NewRequest(r)
+
+	if q := req.QueryValues(); !reflect.DeepEqual(q, r.URL.Query()) {
+		t.Fatalf("unexpected query values: %v", q)
+	}
+	if f := req.PostFormValues(); !reflect.DeepEqual(f, r.PostForm) || f.Get("name") != "clover" || len(f["tag"]) != 2 {
+		t.Fatalf("unexpected post form values: %v", f)
+	}
+}
+
+func TestRequestBindXML(t *testing.T) {
+	type user struct {
+		Name string   `xml:"name"`
+		Tags []string `xml:"tags>tag"`
+	}
+	body := `<user><name>clover</name><tags><tag>a</tag><tag>b</tag></tags></user>`
+
+	r := httptest.NewRequest("POST", "/users", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/soap+xml; charset=utf-8")
+	var u user
+	if err := NewRequest(r).BindXML(&u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "clover" || !reflect.DeepEqual(u.Tags, []string{"a", "b"}) {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+
+	r = httptest.NewRequest("POST", "/users", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if err := NewRequest(r).BindXML(&u); err != ErrNotXML {
+		t.Fatalf("expected ErrNotXML, got %v", err)
+	}
+
+	defer func(n int64) { MaxXMLBodyBytes = n }(MaxXMLBodyBytes)
+	MaxXMLBodyBytes = 16
+	r = httptest.NewRequest("POST", "/users", strings.NewReader(body))
+	if err := NewRequest(r).BindXML(&u); err != ErrBodyTooLarge {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+	}
+}
+
+func TestRequestDeadline(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if _, ok := NewRequest(r).Deadline(); ok {
+		t.Fatal("expected no deadline")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	ctx, cancel := context.WithDeadline(r.Context(), deadline)
+	defer cancel()
+	d, ok := NewRequest(r.WithContext(ctx)).Deadline()
+	if !ok || !d.Equal(deadline) {
+		t.Fatalf("unexpected deadline: %v %v", d, ok)
+	}
+}
+
+func TestWithValue(t *testing.T) {
+	type userKey struct{}
+
+	r := NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, WithRequest(r))
+		})
+	})
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			outer := ReqOf(r.Context())
+			next.ServeHTTP(w, WithValue(r, userKey{}, "alice"))
+			if outer.HTTPRequest() != r || outer.Context().Value(userKey{}) != nil {
+				t.Fatal("expected the outer Request wrapper to be left untouched")
+			}
+		})
+	})
+	r.Method("GET", "/", func(ctx context.Context, r *http.Request) render.Render {
+		req := ReqOf(ctx)
+		if req.Context().Value(userKey{}) != "alice" || req.HTTPRequest() != r {
+			t.Fatal("expected the Request wrapper to follow the request context")
+		}
+		return render.Text(ctx.Value(userKey{}).(string))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Body.String() != "alice" {
+		t.Fatalf("unexpected body: %q", w.Body.String())
+	}
+
+	// without a Request wrapper
+	req := WithValue(httptest.NewRequest("GET", "/", nil), userKey{}, "bob")
+	if NewRequest(req).Context().Value(userKey{}) != "bob" {
+		t.Fatal("expected the value in the request context")
+	}
+}
+
+func TestRequestScheme(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	if s := NewRequest(r).Scheme(); s != "http" {
+		t.Fatalf("expected http, got %q", s)
+	}
+
+	r = httptest.NewRequest("GET", "https://example.com/", nil)
+	if s := NewRequest(r).Scheme(); s != "https" {
+		t.Fatalf("expected https on direct TLS, got %q", s)
+	}
+
+	defer func(trust bool) { TrustForwardedProto = trust }(TrustForwardedProto)
+	r = httptest.NewRequest("GET", "http://example.com/", nil)
+	r.Header.Set("X-Forwarded-Proto", "https, http")
+	TrustForwardedProto = false
+	if s := NewRequest(r).Scheme(); s != "http" {
+		t.Fatalf("expected the untrusted header to be ignored, got %q", s)
+	}
+	TrustForwardedProto = true
+	if s := NewRequest(r).Scheme(); s != "https" {
+		t.Fatalf("expected https behind the proxy, got %q", s)
+	}
+}
+
+func TestRequestAbsoluteURL(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com:8080/users/42?tab=posts", nil)
+	req := NewRequest(r)
+
+	tests := []struct {
+		path      string
+		keepQuery bool
+		expected  string
+	}{
+		{"/login", false, "http://example.com:8080/login"},
+		{"/login", true, "http://example.com:8080/login?tab=posts"},
+		{"/login?next=/home", true, "http://example.com:8080/login?next=/home"},
+		{"edit", false, "http://example.com:8080/users/edit"},
+		{"../posts/1", false, "http://example.com:8080/posts/1"},
+		{"", true, "http://example.com:8080/users/42?tab=posts"},
+	}
+	for _, tc := range tests {
+		if u := req.AbsoluteURL(tc.path, tc.keepQuery); u != tc.expected {
+			t.Fatalf("%q: expected %q, got %q", tc.path, tc.expected, u)
+		}
+	}
+
+	defer func(trust bool) { TrustForwardedProto = trust }(TrustForwardedProto)
+	TrustForwardedProto = true
+	r = httptest.NewRequest("GET", "http://example.com/users/42", nil)
+	r.Header.Set("X-Forwarded-Proto", "https")
+	if u := NewRequest(r).AbsoluteURL("/login", false); u != "https://example.com/login" {
+		t.Fatalf("unexpected proxied URL %q", u)
+	}
+}
+
+func TestRequestParseFormErr(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader("name=clover&bad=%zz"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req := NewRequest(r)
+
+	if err := req.ParseFormErr(); err == nil {
+		t.Fatal("expected an error for the malformed form body")
+	}
+	if v, _ := req.PostForm("name"); v != "clover" {
+		t.Fatalf("unexpected name %q", v)
+	}
+
+	r = httptest.NewRequest("POST", "/", strings.NewReader("name=clover"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := NewRequest(r).ParseFormErr(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequestBytes(t *testing.T) {
+	body := `{"name":"clover"}`
+	req := NewRequest(httptest.NewRequest("POST", "/hook", strings.NewReader(body)))
+
+	b, err := req.Bytes(1024)
+	if err != nil || string(b) != body {
+		t.Fatalf("unexpected body: %q %v", b, err)
+	}
+	var v struct{ Name string }
+	if err := req.JsonUnmarshal(&v); err != nil || v.Name != "clover" {
+		t.Fatalf("expected the cached body to be parsed, got %+v %v", v, err)
+	}
+	if b, _ := req.Bytes(1024); string(b) != body {
+		t.Fatalf("expected the cached body, got %q", b)
+	}
+	if _, err := req.Bytes(4); err != ErrBodyTooLarge {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+	}
+
+	req = NewRequest(httptest.NewRequest("POST", "/hook", strings.NewReader(body)))
+	if _, err := req.Bytes(int64(len(body) - 1)); err != ErrBodyTooLarge {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+	}
+
+	req = NewRequest(httptest.NewRequest("POST", "/hook", strings.NewReader("0123456789")))
+	if _, err := req.Bytes(4); err != ErrBodyTooLarge {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+	}
+	if b, err := io.ReadAll(req.Body()); err != nil || string(b) != "0123456789" {
+		t.Fatalf("expected the full body after ErrBodyTooLarge, got %q %v", b, err)
+	}
+}
@@ -0,0 +1,66 @@
+package clover
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/goclover/clover/render"
+)
+
+// resourceActions maps the RESTful controller methods to their HTTP method
+// and whether they're routed on the `{id}` of a resource.
+var resourceActions = []struct {
+	name   string
+	method string
+	member bool
+}{
+	{"Index", http.MethodGet, false},
+	{"Create", http.MethodPost, false},
+	{"Show", http.MethodGet, true},
+	{"Update", http.MethodPut, true},
+	{"Delete", http.MethodDelete, true},
+}
+
+// Resource wires the RESTful actions of the `ctrl` controller on the `pattern`
+// routing pattern, following the convention:
+//
+//	Index   GET    /pattern
+//	Create  POST   /pattern
+//	Show    GET    /pattern/{id}
+//	Update  PUT    /pattern/{id}
+//	Delete  DELETE /pattern/{id}
+//
+// The actions are the methods of ctrl with these names, either a HandlerFunc
+// or a http.HandlerFunc, and the ones ctrl doesn't have are skipped. The
+// resource id is read with URLParam(r, "id"). It panics if an action has
+// another signature.
+func Resource(r Router, pattern string, ctrl interface{}) {
+	base := strings.TrimSuffix(pattern, "/")
+	v := reflect.ValueOf(ctrl)
+
+	for _, action := range resourceActions {
+		m := v.MethodByName(action.name)
+		if !m.IsValid() {
+			continue
+		}
+
+		p := base
+		if action.member {
+			p += "/{id}"
+		} else if p == "" {
+			p = "/"
+		}
+
+		switch fn := m.Interface().(type) {
+		case func(context.Context, *http.Request) render.Render:
+			r.Method(action.method, p, fn)
+		case func(http.ResponseWriter, *http.Request):
+			r.MethodFunc(action.method, p, fn)
+		default:
+			panic(fmt.Sprintf("clover: invalid signature %T for the %s action of the resource on '%s'", fn, action.name, pattern))
+		}
+	}
+}
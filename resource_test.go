@@ -0,0 +1,61 @@
+package clover
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/goclover/clover/render"
+)
+
+type articlesController struct{}
+
+func (articlesController) Index(ctx context.Context, r *http.Request) render.Render {
+	return render.Text("index")
+}
+
+func (articlesController) Show(ctx context.Context, r *http.Request) render.Render {
+	return render.Text("show " + URLParam(r, "id"))
+}
+
+func (articlesController) Delete(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("delete " + URLParam(r, "id")))
+}
+
+// Helper isn't an action, and is ignored.
+func (articlesController) Helper() {}
+
+type invalidController struct{}
+
+func (invalidController) Create(r *http.Request) {}
+
+func TestResource(t *testing.T) {
+	r := NewRouter()
+	Resource(r, "/articles", articlesController{})
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"GET", "/articles", 200, "index"},
+		{"GET", "/articles/7", 200, "show 7"},
+		{"DELETE", "/articles/7", 200, "delete 7"},
+		{"POST", "/articles", 405, ""},
+		{"PUT", "/articles/7", 405, ""},
+	}
+	for _, tc := range tests {
+		resp, body := testHandler(t, r, tc.method, tc.path, nil)
+		if resp.StatusCode != tc.status || (tc.body != "" && body != tc.body) {
+			t.Fatalf("%s %s: unexpected response %d %q", tc.method, tc.path, resp.StatusCode, body)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for an invalid action signature")
+		}
+	}()
+	Resource(NewRouter(), "/invalid", invalidController{})
+}
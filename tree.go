@@ -111,6 +111,23 @@ type endpoint struct {
 
 	// parameter keys recorded on handler nodes
 	paramKeys []string
+
+	// documentation metadata attached with Mux.Doc
+	doc *RouteDoc
+
+	// explicit is set once a handler is registered for the endpoint method,
+	// rather than inherited from a route matching all methods
+	explicit bool
+}
+
+// methods appends the http methods with a handler to dst.
+func (s endpoints) methods(dst []methodTyp) []methodTyp {
+	for mt, ep := range s {
+		if mt != mALL && mt&mSTUB != mSTUB && ep.handler != nil {
+			dst = append(dst, mt)
+		}
+	}
+	return dst
 }
 
 func (s endpoints) Value(method methodTyp) *endpoint {
@@ -241,7 +258,7 @@ func (n *node) addChild(cloverld *node, prefix string) *node {
 		if segTyp == ntRegexp {
 			rex, err := regexp.Compile(segRexpat)
 			if err != nil {
-				panic(fmt.Sprintf("clover: invalid regexp pattern '%s' in route param", segRexpat))
+				panic(fmt.Sprintf("clover: invalid regexp pattern '%s' in route param: %v", segRexpat, err))
 			}
 			cloverld.prefix = segRexpat
 			cloverld.rex = rex
@@ -335,6 +352,10 @@ func (n *node) setEndpoint(method methodTyp, handler http.Handler, pattern strin
 
 	paramKeys := patParamKeys(pattern)
 
+	if method&mSTUB != mSTUB {
+		n.checkConflicts(method, pattern)
+	}
+
 	if method&mSTUB == mSTUB {
 		n.endpoints.Value(mSTUB).handler = handler
 	}
@@ -343,6 +364,7 @@ func (n *node) setEndpoint(method methodTyp, handler http.Handler, pattern strin
 		h.handler = handler
 		h.pattern = pattern
 		h.paramKeys = paramKeys
+		h.explicit = method&mSTUB != mSTUB
 		for _, m := range methodMap {
 			h := n.endpoints.Value(m)
 			h.handler = handler
@@ -354,9 +376,38 @@ func (n *node) setEndpoint(method methodTyp, handler http.Handler, pattern strin
 		h.handler = handler
 		h.pattern = pattern
 		h.paramKeys = paramKeys
+		h.explicit = method&mSTUB != mSTUB
+	}
+}
+
+// checkConflicts panics if a handler is already registered on the node for the
+// method, naming both patterns when they only differ by their param names. A
+// route for all methods also conflicts with any route registered for a single
+// method, as it would silently replace it.
+func (n *node) checkConflicts(method methodTyp, pattern string) {
+	n.checkEndpoint(method, pattern)
+	if method == mALL {
+		for _, m := range methodMap {
+			n.checkEndpoint(m, pattern)
+		}
 	}
 }
 
+func (n *node) checkEndpoint(method methodTyp, pattern string) {
+	ep := n.endpoints[method]
+	if ep == nil || !ep.explicit || ep.handler == nil {
+		return
+	}
+	if ep.pattern != pattern {
+		panic(fmt.Sprintf("clover: routing pattern '%s' conflicts with '%s', their params are named differently at the same position", pattern, ep.pattern))
+	}
+	m := "*"
+	if method != mALL {
+		m = methodTypString(method)
+	}
+	panic(fmt.Sprintf("clover: route '%s %s' is already registered", m, pattern))
+}
+
 func (n *node) FindRoute(rctx *Context, method methodTyp, path string) (*node, endpoints, http.Handler) {
 	// Reset the context routing pattern and params
 	rctx.routePattern = ""
@@ -457,6 +508,7 @@ func (n *node) findRoute(rctx *Context, method methodTyp, path string) *node {
 						// flag that the routing context found a route, but not a corresponding
 						// supported method
 						rctx.methodNotAllowed = true
+						rctx.methodsAllowed = xn.endpoints.methods(rctx.methodsAllowed)
 					}
 				}
 
@@ -496,6 +548,7 @@ func (n *node) findRoute(rctx *Context, method methodTyp, path string) *node {
 				// flag that the routing context found a route, but not a corresponding
 				// supported method
 				rctx.methodNotAllowed = true
+				rctx.methodsAllowed = xn.endpoints.methods(rctx.methodsAllowed)
 			}
 		}
 
@@ -631,7 +684,24 @@ func (n *node) routes() []Route {
 				hs[m] = h.handler
 			}
 
-			rt := Route{subroutes, hs, p}
+			var docs map[string]*RouteDoc
+			for mt, h := range mh {
+				if h.doc == nil || h.handler == nil {
+					continue
+				}
+				m := "*"
+				if mt != mALL {
+					if m = methodTypString(mt); m == "" {
+						continue
+					}
+				}
+				if docs == nil {
+					docs = make(map[string]*RouteDoc)
+				}
+				docs[m] = h.doc
+			}
+
+			rt := Route{SubRoutes: subroutes, Handlers: hs, Pattern: p, Docs: docs}
 			rts = append(rts, rt)
 		}
 
@@ -712,6 +782,11 @@ func patNextSegment(pattern string) (nodeTyp, string, string, byte, int, int) {
 		}
 
 		if len(rexpat) > 0 {
+			// group unanchored patterns so alternations like {ext:xml|txt}
+			// must match the whole segment
+			if rexpat[0] != '^' && rexpat[len(rexpat)-1] != '$' {
+				rexpat = "(?:" + rexpat + ")"
+			}
 			if rexpat[0] != '^' {
 				rexpat = "^" + rexpat
 			}
@@ -730,14 +805,38 @@ func patNextSegment(pattern string) (nodeTyp, string, string, byte, int, int) {
 	return ntCatchAll, "*", "", 0, ws, len(pattern)
 }
 
+// patCheckRegexps compiles every regexp segment of a pattern, so an invalid
+// one is reported along with the routing pattern at registration time.
+func patCheckRegexps(pattern string) {
+	pat := pattern
+	for {
+		ptyp, _, rexpat, _, _, e := patNextSegment(pat)
+		if ptyp == ntStatic {
+			return
+		}
+		if ptyp == ntRegexp {
+			if _, err := regexp.Compile(rexpat); err != nil {
+				panic(fmt.Sprintf("clover: invalid regexp '%s' in routing pattern '%s': %v", rexpat, pattern, err))
+			}
+		}
+		pat = pat[e:]
+	}
+}
+
 func patParamKeys(pattern string) []string {
 	pat := pattern
 	paramKeys := []string{}
+	anonymous := 0
 	for {
 		ptyp, paramKey, _, _, _, e := patNextSegment(pat)
 		if ptyp == ntStatic {
 			return paramKeys
 		}
+		if paramKey == "" {
+			// anonymous regexp params are keyed by their position, ie. "0", "1"
+			paramKey = strconv.Itoa(anonymous)
+			anonymous++
+		}
 		for i := 0; i < len(paramKeys); i++ {
 			if paramKeys[i] == paramKey {
 				panic(fmt.Sprintf("clover: routing pattern '%s' contains duplicate param key, '%s'", pattern, paramKey))
@@ -818,6 +917,24 @@ type Route struct {
 	SubRoutes Routes
 	Handlers  map[string]http.Handler
 	Pattern   string
+
+	// Docs holds the documentation metadata of the route by method, as
+	// attached with Mux.Doc.
+	Docs map[string]*RouteDoc
+}
+
+// RouteDoc is the documentation metadata of a route, used to generate API
+// specifications such as with the docgen package.
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+
+	// Request and Response are the JSON schemas of the request and response
+	// bodies, ie. a map[string]interface{}, emitted as is.
+	Request  interface{}
+	Response interface{}
 }
 
 // WalkFunc is the type of the function called for each method and route visited by Walk.